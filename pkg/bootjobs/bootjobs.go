@@ -53,7 +53,7 @@ func FindGitOperatorNamespace(client kubernetes.Interface, namespace string) (st
 	if stringhelpers.StringArrayIndex(namespaces, namespace) < 0 {
 		namespaces = append(namespaces, namespace)
 	}
-	name := "jx-git-operator"
+	name := GitOperatorDeploymentName
 	for _, ns := range namespaces {
 		_, err := client.AppsV1().Deployments(ns).Get(context.TODO(), name, metav1.GetOptions{})
 		if err == nil {
@@ -3,4 +3,22 @@ package bootjobs
 const (
 	// LabelCommitSHA the label added to git operator Jobs to indicate the commit sha
 	LabelCommitSHA = "git-operator.jenkins.io/commit-sha"
+
+	// LabelGitOperatorKind the label added to the Secrets the git operator watches for git repositories to boot
+	LabelGitOperatorKind = "git-operator.jenkins.io/kind"
+
+	// GitOperatorKind the value of the LabelGitOperatorKind label on the git operator Secrets
+	GitOperatorKind = "git-operator"
+
+	// DefaultJobSelector the default selector of the boot Jobs
+	DefaultJobSelector = "app=jx-boot"
+
+	// GitOperatorDeploymentName the name of the git operator Deployment
+	GitOperatorDeploymentName = "jx-git-operator"
+
+	// DefaultGitOperatorSelector the default selector of the git operator pod
+	DefaultGitOperatorSelector = "app=jx-git-operator"
 )
+
+// GitOperatorSecretSelector the selector of the Secrets the git operator watches
+var GitOperatorSecretSelector = LabelGitOperatorKind + "=" + GitOperatorKind
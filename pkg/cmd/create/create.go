@@ -8,8 +8,10 @@ import (
 	"github.com/jenkins-x-plugins/jx-admin/pkg/cmd/operator"
 	"github.com/jenkins-x-plugins/jx-admin/pkg/common"
 	"github.com/jenkins-x-plugins/jx-admin/pkg/envfactory"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/gitcreds"
 	"github.com/jenkins-x-plugins/jx-admin/pkg/reqhelpers"
 	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
@@ -168,9 +170,29 @@ func AddGitRequirementsOptions(cmd *cobra.Command, r *jxcore.RequirementsConfig)
 
 // Run implements the command
 func (o *Options) Run() error {
+	err := gitcreds.RejectGitHubApp(o.Operator.GitHubAppID, o.Operator.GitHubAppInstallationID, o.Operator.GitHubAppPrivateKeyFile)
+	if err != nil {
+		return err
+	}
+
+	// lets make sure the SCM client and git use the CA and proxies before we create the git repository
+	if o.HTTPClient == nil {
+		o.HTTPClient, err = o.Operator.Network.HTTPClient()
+		if err != nil {
+			return fmt.Errorf("failed to configure the CA and proxies: %w", err)
+		}
+	}
 
 	if o.Gitter == nil {
-		o.Gitter = cli.NewCLIClient("", o.CommandRunner)
+		runner := o.CommandRunner
+		if runner == nil {
+			runner = cmdrunner.DefaultCommandRunner
+		}
+		runner, err = o.Operator.Network.CommandRunner(runner)
+		if err != nil {
+			return fmt.Errorf("failed to configure the CA and proxies for git: %w", err)
+		}
+		o.Gitter = cli.NewCLIClient("", runner)
 	}
 
 	if o.DevGitURL != "" {
@@ -325,6 +347,9 @@ func (o *Options) installGitOperator(dir string) error {
 	op.GitURL = gitURL
 	op.GitUserName = o.ScmClientFactory.GitUsername
 	op.GitToken = o.ScmClientFactory.GitToken
+	if op.GitKind == "" {
+		op.GitKind = o.Requirements.Spec.Cluster.GitKind
+	}
 	err := op.Run()
 	if err != nil {
 		return fmt.Errorf("failed to install the git operator: %w", err)
@@ -0,0 +1,368 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/bootjobs"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/cmd/joblog"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/common"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+
+	"github.com/spf13/cobra"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// IndexFileName the name of the index file in the archive
+	IndexFileName = "index.json"
+
+	// UnknownCommitSHA the key in the index of Jobs without a commit SHA label
+	UnknownCommitSHA = "unknown"
+)
+
+// Options contains the command line arguments for this command
+type Options struct {
+	options.BaseOptions
+
+	Namespace   string
+	JobSelector string
+	CommitSHA   string
+	Output      string
+	Since       time.Duration
+	Limit       int
+	KubeClient  kubernetes.Interface
+	Index       *Index
+}
+
+// Index the index of the archive keyed by the git commit SHA of the boot Jobs
+type Index struct {
+	Namespace string                `json:"namespace"`
+	Selector  string                `json:"selector"`
+	Created   time.Time             `json:"created"`
+	Commits   map[string][]JobEntry `json:"commits"`
+}
+
+// JobEntry the archived files of a boot Job
+type JobEntry struct {
+	Name     string     `json:"name"`
+	Status   string     `json:"status"`
+	Created  time.Time  `json:"created"`
+	Duration string     `json:"duration,omitempty"`
+	Path     string     `json:"path"`
+	Pods     []PodEntry `json:"pods,omitempty"`
+}
+
+// PodEntry the archived files of a boot Job pod
+type PodEntry struct {
+	Name  string            `json:"name"`
+	Phase string            `json:"phase"`
+	Logs  map[string]string `json:"logs,omitempty"`
+}
+
+var (
+	info = termcolor.ColorInfo
+
+	cmdLong = templates.LongDesc(`
+		Archives the boot Job logs so that they are available after the boot Job pods have been garbage collected
+
+		For each boot Job the logs of every container of its pods are saved along with the YAML of the Job and its pods
+		and the related events. An index.json file lists the archived Jobs keyed by the git commit SHA they booted.
+
+		If the output path ends in .tar.gz or .tgz a gzipped tarball is created otherwise the files are saved in the directory.
+`)
+
+	cmdExample = templates.Examples(`
+* archives the logs of all the boot Jobs into the boot-logs directory
+` + bashExample("log archive") + `
+* archives the logs of the boot Jobs from the last day into a tarball
+` + bashExample("log archive --since 24h -o boot-logs.tar.gz") + `
+* archives the logs of the boot Jobs of a commit
+` + bashExample("log archive --commit-sha 1a2b3c4d") + `
+`)
+)
+
+// bashExample returns markdown for a bash script expression
+func bashExample(cli string) string {
+	return fmt.Sprintf("\n```bash \n%s %s\n```\n", common.BinaryName, cli)
+}
+
+// NewCmdArchive creates the command
+func NewCmdArchive() (*cobra.Command, *Options) {
+	o := &Options{}
+	command := &cobra.Command{
+		Use:     "archive",
+		Short:   "archives the boot Job logs, Job and Pod YAML and events into a directory or tarball",
+		Long:    cmdLong,
+		Example: cmdExample,
+		Run: func(command *cobra.Command, args []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	command.Flags().StringVarP(&o.Namespace, "namespace", "n", "", "the namespace where the boot jobs run. If not specified it will look in: jx-git-operator and jx")
+	command.Flags().StringVarP(&o.JobSelector, "selector", "s", bootjobs.DefaultJobSelector, "the selector of the boot Jobs")
+	command.Flags().StringVarP(&o.CommitSHA, "commit-sha", "", "", "only archive the boot Jobs for the git commit SHA")
+	command.Flags().StringVarP(&o.Output, "output", "o", "boot-logs", "the directory to archive to. If it ends in .tar.gz or .tgz a gzipped tarball is created instead")
+	command.Flags().DurationVarP(&o.Since, "since", "", 0, "only archive the boot Jobs created within this duration such as 24h")
+	command.Flags().IntVarP(&o.Limit, "limit", "", 0, "the maximum number of the most recent boot Jobs to archive. If zero all the boot Jobs are archived")
+
+	o.BaseOptions.AddBaseFlags(command)
+
+	return command, o
+}
+
+// Run implements the command
+func (o *Options) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return err
+	}
+
+	client := o.KubeClient
+	ns, err := bootjobs.FindGitOperatorNamespace(client, o.Namespace)
+	if err != nil {
+		return fmt.Errorf("failed to find the git operator namespace: %w", err)
+	}
+
+	sortedJobs, err := bootjobs.GetSortedJobs(client, ns, o.JobSelector, o.CommitSHA)
+	if err != nil {
+		return fmt.Errorf("failed to get jobs: %w", err)
+	}
+	sortedJobs = o.filterJobs(sortedJobs)
+	if len(sortedJobs) == 0 {
+		log.Logger().Infof("there are no boot Jobs to archive in namespace %s with selector %s", info(ns), info(o.JobSelector))
+		return nil
+	}
+
+	w, err := newWriter(o.Output)
+	if err != nil {
+		return err
+	}
+
+	o.Index = &Index{
+		Namespace: ns,
+		Selector:  o.JobSelector,
+		Created:   time.Now().UTC(),
+		Commits:   map[string][]JobEntry{},
+	}
+	for i := range sortedJobs {
+		job := &sortedJobs[i]
+		entry, err := o.archiveJob(w, job)
+		if err != nil {
+			w.Close()
+			return fmt.Errorf("failed to archive boot Job %s: %w", job.Name, err)
+		}
+		sha := job.Labels[bootjobs.LabelCommitSHA]
+		if sha == "" {
+			sha = UnknownCommitSHA
+		}
+		o.Index.Commits[sha] = append(o.Index.Commits[sha], *entry)
+	}
+
+	data, err := json.MarshalIndent(o.Index, "", "  ")
+	if err != nil {
+		w.Close()
+		return fmt.Errorf("failed to marshal the index to JSON: %w", err)
+	}
+	err = w.WriteFile(IndexFileName, data)
+	if err != nil {
+		w.Close()
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+	log.Logger().Infof("archived %d boot Jobs from namespace %s to %s", len(sortedJobs), info(ns), info(o.Output))
+	return nil
+}
+
+// Validate verifies the settings are correct and we can lazy create any required resources
+func (o *Options) Validate() error {
+	if o.Output == "" {
+		return options.MissingOption("output")
+	}
+	if o.Limit < 0 {
+		return options.InvalidOptionf("limit", o.Limit, "must not be negative")
+	}
+	if o.JobSelector == "" {
+		o.JobSelector = bootjobs.DefaultJobSelector
+	}
+	var err error
+	o.KubeClient, err = kube.LazyCreateKubeClientWithMandatory(o.KubeClient, true)
+	if err != nil {
+		return fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+	return nil
+}
+
+// filterJobs filters the newest first Jobs by their age and the limit
+func (o *Options) filterJobs(sortedJobs []batchv1.Job) []batchv1.Job {
+	if o.Since > 0 {
+		var answer []batchv1.Job
+		after := time.Now().Add(-o.Since)
+		for i := range sortedJobs {
+			if sortedJobs[i].CreationTimestamp.After(after) {
+				answer = append(answer, sortedJobs[i])
+			}
+		}
+		sortedJobs = answer
+	}
+	if o.Limit > 0 && len(sortedJobs) > o.Limit {
+		sortedJobs = sortedJobs[:o.Limit]
+	}
+	return sortedJobs
+}
+
+func (o *Options) archiveJob(w writer, job *batchv1.Job) (*JobEntry, error) {
+	ctx := context.TODO()
+	client := o.KubeClient
+	ns := job.Namespace
+	dir := "jobs/" + job.Name
+
+	log.Logger().Infof("archiving boot Job %s", info(job.Name))
+
+	entry := &JobEntry{
+		Name:    job.Name,
+		Status:  joblog.JobStatus(job),
+		Created: job.CreationTimestamp.UTC(),
+		Path:    dir,
+	}
+	if d := joblog.JobDuration(job); d > 0 {
+		entry.Duration = d.String()
+	}
+
+	job.APIVersion = "batch/v1"
+	job.Kind = "Job"
+	err := writeYAML(w, dir+"/job.yaml", job)
+	if err != nil {
+		return nil, err
+	}
+
+	podList, err := client.CoreV1().Pods(ns).List(ctx, metav1.ListOptions{
+		LabelSelector: "job-name=" + job.Name,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods in namespace %s for Job %s: %w", ns, job.Name, err)
+	}
+	podItems := podList.Items
+	sort.Slice(podItems, func(i, j int) bool {
+		return podItems[i].CreationTimestamp.Before(&podItems[j].CreationTimestamp)
+	})
+
+	involvedNames := []string{job.Name}
+	for i := range podItems {
+		pod := &podItems[i]
+		involvedNames = append(involvedNames, pod.Name)
+		podEntry, err := o.archivePod(ctx, w, dir+"/pods/"+pod.Name, pod)
+		if err != nil {
+			return nil, err
+		}
+		entry.Pods = append(entry.Pods, *podEntry)
+	}
+
+	events, err := o.getEvents(ctx, ns, involvedNames)
+	if err != nil {
+		return nil, err
+	}
+	err = writeYAML(w, dir+"/events.yaml", events)
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (o *Options) archivePod(ctx context.Context, w writer, dir string, pod *corev1.Pod) (*PodEntry, error) {
+	entry := &PodEntry{
+		Name:  pod.Name,
+		Phase: string(pod.Status.Phase),
+		Logs:  map[string]string{},
+	}
+
+	pod.APIVersion = "v1"
+	pod.Kind = "Pod"
+	err := writeYAML(w, dir+"/pod.yaml", pod)
+	if err != nil {
+		return nil, err
+	}
+
+	var containers []string
+	for i := range pod.Spec.InitContainers {
+		containers = append(containers, pod.Spec.InitContainers[i].Name)
+	}
+	for i := range pod.Spec.Containers {
+		containers = append(containers, pod.Spec.Containers[i].Name)
+	}
+	for _, c := range containers {
+		data, err := o.KubeClient.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{Container: c}).DoRaw(ctx)
+		if err != nil {
+			// the container may not have started or its log may have been removed
+			log.Logger().Warnf("failed to get the log of container %s in pod %s: %s", c, pod.Name, err.Error())
+			continue
+		}
+		path := dir + "/" + c + ".log"
+		err = w.WriteFile(path, data)
+		if err != nil {
+			return nil, err
+		}
+		entry.Logs[c] = path
+	}
+	return entry, nil
+}
+
+// getEvents returns the events for the Job and its pods in time order
+func (o *Options) getEvents(ctx context.Context, ns string, names []string) (*corev1.EventList, error) {
+	answer := &corev1.EventList{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "List",
+		},
+	}
+	for _, name := range names {
+		selector := fields.OneTermEqualSelector("involvedObject.name", name).String()
+		eventList, err := o.KubeClient.CoreV1().Events(ns).List(ctx, metav1.ListOptions{
+			FieldSelector: selector,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list events in namespace %s with selector %s: %w", ns, selector, err)
+		}
+		for i := range eventList.Items {
+			event := eventList.Items[i]
+			if event.InvolvedObject.Name != name {
+				continue
+			}
+			event.APIVersion = "v1"
+			event.Kind = "Event"
+			answer.Items = append(answer.Items, event)
+		}
+	}
+	sort.SliceStable(answer.Items, func(i, j int) bool {
+		return answer.Items[i].LastTimestamp.Before(&answer.Items[j].LastTimestamp)
+	})
+	return answer, nil
+}
+
+func writeYAML(w writer, path string, obj interface{}) error {
+	if m, ok := obj.(metav1.Object); ok {
+		m.SetManagedFields(nil)
+	}
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s to YAML: %w", path, err)
+	}
+	return w.WriteFile(path, data)
+}
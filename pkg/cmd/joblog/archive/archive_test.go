@@ -0,0 +1,178 @@
+package archive_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/bootjobs"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/cmd/joblog/archive"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const ns = "jx-git-operator"
+
+func TestArchiveDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "boot-logs")
+
+	_, o := archive.NewCmdArchive()
+	o.KubeClient = fake.NewSimpleClientset(createObjects()...)
+	o.Output = dir
+
+	err := o.Run()
+	require.NoError(t, err, "failed to archive the boot logs")
+
+	data, err := os.ReadFile(filepath.Join(dir, archive.IndexFileName))
+	require.NoError(t, err, "failed to load the index")
+	index := &archive.Index{}
+	err = json.Unmarshal(data, index)
+	require.NoError(t, err, "failed to parse the index")
+
+	assert.Equal(t, ns, index.Namespace, "index namespace")
+	require.Len(t, index.Commits, 2, "index commits")
+	require.Len(t, index.Commits["abc123"], 1, "Jobs for commit abc123")
+	entry := index.Commits["abc123"][0]
+	assert.Equal(t, "jx-boot-abc", entry.Name, "job name")
+	assert.Equal(t, "Failed", entry.Status, "job status")
+	require.Len(t, entry.Pods, 1, "pods")
+	assert.Equal(t, "jobs/jx-boot-abc/pods/jx-boot-abc-1/job.log", entry.Pods[0].Logs["job"], "pod log path")
+	require.Len(t, index.Commits["def456"], 1, "Jobs for commit def456")
+
+	for _, path := range []string{
+		"jobs/jx-boot-abc/job.yaml",
+		"jobs/jx-boot-abc/events.yaml",
+		"jobs/jx-boot-abc/pods/jx-boot-abc-1/pod.yaml",
+		"jobs/jx-boot-abc/pods/jx-boot-abc-1/job.log",
+		"jobs/jx-boot-def/job.yaml",
+	} {
+		assert.FileExists(t, filepath.Join(dir, filepath.FromSlash(path)))
+	}
+
+	data, err = os.ReadFile(filepath.Join(dir, "jobs", "jx-boot-abc", "events.yaml"))
+	require.NoError(t, err, "failed to load the events")
+	assert.Contains(t, string(data), "BackoffLimitExceeded", "events")
+	assert.NotContains(t, string(data), "SuccessfulCreate", "events of other Jobs")
+}
+
+func TestArchiveTarball(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "boot-logs.tar.gz")
+
+	_, o := archive.NewCmdArchive()
+	o.KubeClient = fake.NewSimpleClientset(createObjects()...)
+	o.Output = file
+	o.CommitSHA = "def456"
+
+	err := o.Run()
+	require.NoError(t, err, "failed to archive the boot logs")
+
+	f, err := os.Open(file)
+	require.NoError(t, err, "failed to open tarball")
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err, "failed to read gzip stream")
+	tr := tar.NewReader(gz)
+
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err, "failed to read tarball")
+		names = append(names, header.Name)
+	}
+	assert.Contains(t, names, "boot-logs/index.json", "tarball files")
+	assert.Contains(t, names, "boot-logs/jobs/jx-boot-def/job.yaml", "tarball files")
+	assert.NotContains(t, names, "boot-logs/jobs/jx-boot-abc/job.yaml", "tarball files")
+}
+
+func createObjects() []runtime.Object {
+	now := time.Now()
+	return []runtime.Object{
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      bootjobs.GitOperatorDeploymentName,
+				Namespace: ns,
+			},
+		},
+		createJob("jx-boot-abc", "abc123", now.Add(-time.Hour), batchv1.JobFailed),
+		createJob("jx-boot-def", "def456", now, batchv1.JobComplete),
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "jx-boot-abc-1",
+				Namespace: ns,
+				Labels: map[string]string{
+					"job-name": "jx-boot-abc",
+				},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name: "job",
+					},
+				},
+			},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodFailed,
+			},
+		},
+		&corev1.Event{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "jx-boot-abc.1",
+				Namespace: ns,
+			},
+			InvolvedObject: corev1.ObjectReference{
+				Kind: "Job",
+				Name: "jx-boot-abc",
+			},
+			Reason:  "BackoffLimitExceeded",
+			Message: "Job has reached the specified backoff limit",
+		},
+		&corev1.Event{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "jx-boot-def.1",
+				Namespace: ns,
+			},
+			InvolvedObject: corev1.ObjectReference{
+				Kind: "Job",
+				Name: "jx-boot-def",
+			},
+			Reason:  "SuccessfulCreate",
+			Message: "Created pod: jx-boot-def-1",
+		},
+	}
+}
+
+func createJob(name, sha string, created time.Time, conditionType batchv1.JobConditionType) *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         ns,
+			CreationTimestamp: metav1.NewTime(created),
+			Labels: map[string]string{
+				"app":                   "jx-boot",
+				bootjobs.LabelCommitSHA: sha,
+			},
+		},
+		Status: batchv1.JobStatus{
+			Conditions: []batchv1.JobCondition{
+				{
+					Type:   conditionType,
+					Status: corev1.ConditionTrue,
+				},
+			},
+		},
+	}
+}
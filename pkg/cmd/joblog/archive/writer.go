@@ -0,0 +1,119 @@
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// writer writes the files of the archive
+type writer interface {
+	// WriteFile writes the file at the path relative to the root of the archive
+	WriteFile(path string, data []byte) error
+
+	// Close finishes writing the archive
+	Close() error
+}
+
+// IsTarball returns true if the path is a gzipped tarball rather than a directory
+func IsTarball(path string) bool {
+	return strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz")
+}
+
+// newWriter creates a writer for a directory or a gzipped tarball depending on the path
+func newWriter(path string) (writer, error) {
+	if !IsTarball(path) {
+		err := os.MkdirAll(path, os.ModePerm)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create directory %s: %w", path, err)
+		}
+		return &dirWriter{dir: path}, nil
+	}
+
+	dir := filepath.Dir(path)
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file %s: %w", path, err)
+	}
+	gz := gzip.NewWriter(f)
+	return &tarWriter{
+		file:   f,
+		gzip:   gz,
+		tar:    tar.NewWriter(gz),
+		prefix: strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".tgz"), ".tar.gz"),
+		now:    time.Now(),
+	}, nil
+}
+
+type dirWriter struct {
+	dir string
+}
+
+func (w *dirWriter) WriteFile(path string, data []byte) error {
+	fileName := filepath.Join(w.dir, filepath.FromSlash(path))
+	err := os.MkdirAll(filepath.Dir(fileName), os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", fileName, err)
+	}
+	err = os.WriteFile(fileName, data, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to save file %s: %w", fileName, err)
+	}
+	return nil
+}
+
+func (w *dirWriter) Close() error {
+	return nil
+}
+
+type tarWriter struct {
+	file   *os.File
+	gzip   *gzip.Writer
+	tar    *tar.Writer
+	prefix string
+	now    time.Time
+}
+
+func (w *tarWriter) WriteFile(path string, data []byte) error {
+	name := w.prefix + "/" + path
+	err := w.tar.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o600,
+		Size:    int64(len(data)),
+		ModTime: w.now,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write tar header for %s: %w", name, err)
+	}
+	_, err = w.tar.Write(data)
+	if err != nil {
+		return fmt.Errorf("failed to write %s to tarball: %w", name, err)
+	}
+	return nil
+}
+
+func (w *tarWriter) Close() error {
+	err := w.tar.Close()
+	if err != nil {
+		w.file.Close()
+		return fmt.Errorf("failed to close tarball: %w", err)
+	}
+	err = w.gzip.Close()
+	if err != nil {
+		w.file.Close()
+		return fmt.Errorf("failed to close gzip stream: %w", err)
+	}
+	err = w.file.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close file %s: %w", w.file.Name(), err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package joblog
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/loganalyser"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	logger "github.com/jenkins-x/jx-logging/v3/pkg/log"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+)
+
+// loadRules loads the built-in diagnosis rules and any user supplied rules files
+func (o *Options) loadRules() error {
+	if o.NoDiagnose {
+		return nil
+	}
+	o.rules = loganalyser.DefaultRules()
+	for _, path := range o.RuleFiles {
+		rules, err := loganalyser.LoadRules(path)
+		if err != nil {
+			return err
+		}
+		o.rules = loganalyser.MergeRules(o.rules, rules)
+	}
+	return nil
+}
+
+// startDiagnosis starts matching the log lines of the boot Job against the diagnosis rules
+func (o *Options) startDiagnosis() error {
+	o.diagnoser = nil
+	if o.NoDiagnose || len(o.rules) == 0 {
+		return nil
+	}
+	var err error
+	o.diagnoser, err = loganalyser.NewDiagnoser(o.rules)
+	if err != nil {
+		return fmt.Errorf("failed to create the diagnosis rules: %w", err)
+	}
+	return nil
+}
+
+// diagnose matches the events of the failed boot Job and its pods against the diagnosis rules then displays
+// the diagnosis of the failure
+func (o *Options) diagnose(client kubernetes.Interface, ns, jobName string) {
+	d := o.diagnoser
+	if d == nil {
+		return
+	}
+	o.diagnoser = nil
+	if o.imagePulls != nil {
+		o.imagePulls.addToDiagnoser(d)
+	}
+
+	ctx := context.TODO()
+	names := []string{jobName}
+	podList, err := client.CoreV1().Pods(ns).List(ctx, metav1.ListOptions{
+		LabelSelector: "job-name=" + jobName,
+	})
+	if err != nil {
+		logger.Logger().Warnf("failed to list the pods of Job %s in namespace %s: %s", jobName, ns, err.Error())
+	} else {
+		for i := range podList.Items {
+			pod := &podList.Items[i]
+			names = append(names, pod.Name)
+			addContainerWaitingReasons(d, pod)
+		}
+	}
+	for _, name := range names {
+		eventList, err := client.CoreV1().Events(ns).List(ctx, metav1.ListOptions{
+			FieldSelector: fields.OneTermEqualSelector("involvedObject.name", name).String(),
+		})
+		if err != nil {
+			logger.Logger().Warnf("failed to list the events of %s in namespace %s: %s", name, ns, err.Error())
+			continue
+		}
+		for i := range eventList.Items {
+			event := &eventList.Items[i]
+			if event.InvolvedObject.Name == name {
+				d.AddEvent(event.Reason, event.Message)
+			}
+		}
+	}
+
+	diagnoses := d.Diagnoses()
+	if len(diagnoses) == 0 {
+		logger.Logger().Debugf("no diagnosis rules matched the failure of boot Job %s", jobName)
+		return
+	}
+	if o.JSONOutput() {
+		o.emit(&Event{
+			Type:      EventDiagnosis,
+			Namespace: ns,
+			Job:       jobName,
+			Diagnoses: diagnoses,
+		})
+		return
+	}
+
+	fmt.Fprintf(o.Out, "\n%s\n", termcolor.ColorWarning("diagnosis of the boot Job failure:"))
+	for _, diagnosis := range diagnoses {
+		fmt.Fprintf(o.Out, "\n* %s (%s)\n", termcolor.ColorError(diagnosis.Description), diagnosis.Name)
+		fmt.Fprintf(o.Out, "  matched: %s\n", diagnosis.Evidence)
+		fmt.Fprintf(o.Out, "  fix:     %s\n", info(diagnosis.Fix))
+		if diagnosis.DocURL != "" {
+			fmt.Fprintf(o.Out, "  see:     %s\n", diagnosis.DocURL)
+		}
+	}
+	fmt.Fprintln(o.Out)
+}
+
+// addContainerWaitingReasons adds the reasons any containers of the pod are waiting such as ErrImagePull in case
+// the events have expired
+func addContainerWaitingReasons(d *loganalyser.Diagnoser, pod *corev1.Pod) {
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for i := range statuses {
+		waiting := statuses[i].State.Waiting
+		if waiting != nil && waiting.Reason != "" {
+			d.AddEvent(waiting.Reason, waiting.Message)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package joblog
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/bootjobs"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/loganalyser"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/jobs"
+	logger "github.com/jenkins-x/jx-logging/v3/pkg/log"
+
+	batchv1 "k8s.io/api/batch/v1"
+)
+
+// OutputFormats the supported output formats
+var OutputFormats = []string{"json"}
+
+const (
+	// EventOperatorReady the git operator pod is ready
+	EventOperatorReady = "operator-ready"
+
+	// EventJobSelected the boot Job to log has been selected
+	EventJobSelected = "job-selected"
+
+	// EventPodStatus the status of a boot Job pod has changed
+	EventPodStatus = "pod-status"
+
+	// EventLog a line of the boot Job pod log
+	EventLog = "log"
+
+	// EventJobFinished the boot Job has finished
+	EventJobFinished = "job-finished"
+
+	// EventSummary the summary of the boot Job pod log when using --summary
+	EventSummary = "summary"
+
+	// EventDiagnosis the diagnosis of a failed boot Job
+	EventDiagnosis = "diagnosis"
+)
+
+// Event a machine readable event written as a line of JSON when using --output json
+type Event struct {
+	// Type the kind of event such as log or job-finished
+	Type string `json:"type"`
+
+	// Time when the event occurred
+	Time time.Time `json:"time"`
+
+	// Namespace the namespace of the git operator and boot Jobs
+	Namespace string `json:"namespace,omitempty"`
+
+	// Job the name of the boot Job
+	Job string `json:"job,omitempty"`
+
+	// CommitSHA the git commit SHA the boot Job is running for
+	CommitSHA string `json:"commitSHA,omitempty"`
+
+	// Pod the name of the pod
+	Pod string `json:"pod,omitempty"`
+
+	// Container the name of the container
+	Container string `json:"container,omitempty"`
+
+	// Previous true if the log line is from the previous terminated instance of the container
+	Previous bool `json:"previous,omitempty"`
+
+	// Status the status of the pod
+	Status string `json:"status,omitempty"`
+
+	// Line the log line
+	Line string `json:"line,omitempty"`
+
+	// Result the result of a finished boot Job: Succeeded or Failed
+	Result string `json:"result,omitempty"`
+
+	// Duration how long the boot Job ran for
+	Duration string `json:"duration,omitempty"`
+
+	// DurationSeconds how long the boot Job ran for in seconds
+	DurationSeconds float64 `json:"durationSeconds,omitempty"`
+
+	// Summary the summary of the boot Job pod log
+	Summary *loganalyser.Summary `json:"summary,omitempty"`
+
+	// Diagnoses the diagnoses of a failed boot Job
+	Diagnoses []*loganalyser.Diagnosis `json:"diagnoses,omitempty"`
+}
+
+// JSONOutput returns true if the events should be written as JSON
+func (o *Options) JSONOutput() bool {
+	return o.Output == "json"
+}
+
+// emit writes the event to the output as a line of JSON if JSON output is enabled
+func (o *Options) emit(e *Event) {
+	if !o.JSONOutput() {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		logger.Logger().Warnf("failed to marshal %s event to JSON: %s", e.Type, err.Error())
+		return
+	}
+	fmt.Fprintln(o.Out, string(data))
+}
+
+// emitJobSelected emits the event for the boot Job being logged
+func (o *Options) emitJobSelected(ns string, job *batchv1.Job) {
+	o.emit(&Event{
+		Type:      EventJobSelected,
+		Namespace: ns,
+		Job:       job.Name,
+		CommitSHA: job.Labels[bootjobs.LabelCommitSHA],
+		Status:    JobStatus(job),
+	})
+}
+
+// emitJobFinished emits the event for a finished boot Job
+func (o *Options) emitJobFinished(job *batchv1.Job) {
+	d := JobDuration(job)
+	o.emit(&Event{
+		Type:            EventJobFinished,
+		Namespace:       job.Namespace,
+		Job:             job.Name,
+		CommitSHA:       job.Labels[bootjobs.LabelCommitSHA],
+		Result:          JobStatus(job),
+		Duration:        d.String(),
+		DurationSeconds: d.Seconds(),
+	})
+}
+
+// JobDuration returns how long the Job has been running or ran for if it has finished
+func JobDuration(job *batchv1.Job) time.Duration {
+	start := job.CreationTimestamp.Time
+	if job.Status.StartTime != nil {
+		start = job.Status.StartTime.Time
+	}
+	if start.IsZero() {
+		return 0
+	}
+	end := time.Now()
+	if job.Status.CompletionTime != nil {
+		end = job.Status.CompletionTime.Time
+	} else if jobs.IsJobFinished(job) {
+		for i := range job.Status.Conditions {
+			c := &job.Status.Conditions[i]
+			if (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) && !c.LastTransitionTime.IsZero() {
+				end = c.LastTransitionTime.Time
+			}
+		}
+	}
+	return end.Sub(start).Round(time.Second)
+}
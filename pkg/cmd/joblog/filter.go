@@ -0,0 +1,64 @@
+package joblog
+
+import (
+	"regexp"
+	"time"
+)
+
+// filteredLine a log line which may be written
+type filteredLine struct {
+	time time.Time
+	text string
+	// separator true if this is a separator between non adjacent groups of matching lines
+	separator bool
+}
+
+// lineFilter filters the lines of a log by a regular expression like grep including any context lines
+// before and after each matching line
+type lineFilter struct {
+	regex   *regexp.Regexp
+	context int
+	before  []filteredLine
+	after   int
+	written bool
+	skipped bool
+}
+
+func newLineFilter(regex *regexp.Regexp, context int) *lineFilter {
+	return &lineFilter{
+		regex:   regex,
+		context: context,
+	}
+}
+
+// filter returns the lines to write for the next line of the log
+func (f *lineFilter) filter(t time.Time, text string) []filteredLine {
+	line := filteredLine{time: t, text: text}
+	if f.regex.MatchString(text) {
+		var answer []filteredLine
+		if f.written && f.skipped && f.context > 0 {
+			answer = append(answer, filteredLine{text: "--", separator: true})
+		}
+		answer = append(answer, f.before...)
+		answer = append(answer, line)
+		f.before = nil
+		f.after = f.context
+		f.written = true
+		f.skipped = false
+		return answer
+	}
+	if f.after > 0 {
+		f.after--
+		return []filteredLine{line}
+	}
+	if f.context > 0 {
+		f.before = append(f.before, line)
+		if len(f.before) > f.context {
+			f.before = f.before[1:]
+			f.skipped = true
+		}
+		return nil
+	}
+	f.skipped = true
+	return nil
+}
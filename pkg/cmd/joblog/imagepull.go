@@ -0,0 +1,128 @@
+package joblog
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/loganalyser"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	logger "github.com/jenkins-x/jx-logging/v3/pkg/log"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// imagePullFailure a container of a boot Job pod which cannot pull its image
+type imagePullFailure struct {
+	pod       string
+	container string
+	image     string
+	reason    string
+	message   string
+	since     time.Time
+}
+
+func (f imagePullFailure) Error() string {
+	return fmt.Sprintf("container %s of pod %s cannot pull image %s: %s: %s", f.container, f.pod, f.image, f.reason, f.message)
+}
+
+// imagePullTracker tracks how long the containers of the boot Job pods have been failing to pull their images.
+// Image pull failures are often transient such as a registry rate limit so we only give up once a container has
+// been failing for longer than the image pull timeout. The tracker is shared by the pod watch and the goroutine
+// checking the timeout so it is safe for concurrent use
+type imagePullTracker struct {
+	lock     sync.Mutex
+	failures map[string]*imagePullFailure
+}
+
+func newImagePullTracker() *imagePullTracker {
+	return &imagePullTracker{
+		failures: map[string]*imagePullFailure{},
+	}
+}
+
+// update records the containers of the pod which cannot pull their images and forgets any which have recovered
+func (t *imagePullTracker) update(pod *corev1.Pod) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	failures := map[string]*imagePullFailure{}
+	for k, f := range t.failures {
+		if f.pod != pod.Name {
+			failures[k] = f
+		}
+	}
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for i := range statuses {
+		s := &statuses[i]
+		waiting := s.State.Waiting
+		if waiting == nil || (waiting.Reason != "ErrImagePull" && waiting.Reason != "ImagePullBackOff") {
+			continue
+		}
+		key := pod.Name + "/" + s.Name
+		f := t.failures[key]
+		if f == nil {
+			f = &imagePullFailure{
+				pod:       pod.Name,
+				container: s.Name,
+				since:     time.Now(),
+			}
+			logger.Logger().Warnf("container %s of pod %s cannot pull image %s: %s", termcolor.ColorInfo(s.Name), termcolor.ColorInfo(pod.Name), termcolor.ColorInfo(s.Image), termcolor.ColorWarning(waiting.Reason))
+		}
+		f.image = s.Image
+		f.reason = waiting.Reason
+		f.message = waiting.Message
+		failures[key] = f
+	}
+	t.failures = failures
+}
+
+// remove forgets any image pull failures of the pod such as when it is deleted
+func (t *imagePullTracker) remove(podName string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for k, f := range t.failures {
+		if f.pod == podName {
+			delete(t.failures, k)
+		}
+	}
+}
+
+// check returns an error if a container has been failing to pull its image for longer than the timeout.
+// If the timeout is not positive we never give up early
+func (t *imagePullTracker) check(timeout time.Duration) error {
+	if timeout <= 0 {
+		return nil
+	}
+	for _, f := range t.list() {
+		if time.Since(f.since) >= timeout {
+			return fmt.Errorf("%w for more than %s", f, timeout.String())
+		}
+	}
+	return nil
+}
+
+// list returns the current image pull failures, oldest first
+func (t *imagePullTracker) list() []imagePullFailure {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	answer := make([]imagePullFailure, 0, len(t.failures))
+	for _, f := range t.failures {
+		answer = append(answer, *f)
+	}
+	sort.Slice(answer, func(i, j int) bool {
+		return answer[i].since.Before(answer[j].since)
+	})
+	return answer
+}
+
+// addToDiagnoser adds the image pull failures to the diagnoser in case the pods have since been deleted
+// or the events have expired
+func (t *imagePullTracker) addToDiagnoser(d *loganalyser.Diagnoser) {
+	for _, f := range t.list() {
+		d.AddEvent(f.reason, f.message)
+	}
+}
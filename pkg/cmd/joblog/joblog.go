@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"regexp"
 	"sort"
 	"strings"
 	"time"
@@ -13,18 +14,17 @@ import (
 	"github.com/jenkins-x-plugins/jx-admin/pkg/common"
 
 	"github.com/jenkins-x-plugins/jx-admin/pkg/bootjobs"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/kubeconfig"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/loganalyser"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/input"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/input/inputfactory"
-	"github.com/jenkins-x/jx-helpers/v3/pkg/kube"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/jobs"
-	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/podlogs"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/pods"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
-	"github.com/jenkins-x/jx-kube-client/v3/pkg/kubeclient"
 	logger "github.com/jenkins-x/jx-logging/v3/pkg/log"
 
 	"github.com/spf13/cobra"
@@ -45,17 +45,42 @@ type Options struct {
 	GitOperatorSelector string
 	ContainerName       string
 	CommitSHA           string
+	JobName             string
+	JobIndex            int
+	LatestFailed        bool
+	LatestSucceeded     bool
 	Duration            time.Duration
 	PollPeriod          time.Duration
+	ImagePullTimeout    time.Duration
 	NoTail              bool
 	ShaMode             bool
+	AllContainers       bool
+	Previous            bool
 	WaitMode            bool
+	NoWatch             bool
+	Output              string
+	Summary             bool
+	Timestamps          bool
+	Since               time.Duration
+	SinceTime           string
+	TailLines           int64
+	Grep                string
+	GrepContext         int
+	NoDiagnose          bool
+	RuleFiles           []string
 	ErrOut              io.Writer
 	Out                 io.Writer
+	KubeConfig          kubeconfig.Options
 	KubeClient          kubernetes.Interface
 	Input               input.Interface
 	timeEnd             time.Time
 	podStatusMap        map[string]string
+	imagePulls          *imagePullTracker
+	analyser            *loganalyser.Analyser
+	rules               []*loganalyser.Rule
+	diagnoser           *loganalyser.Diagnoser
+	sinceTime           *metav1.Time
+	grepRegex           *regexp.Regexp
 }
 
 var (
@@ -64,11 +89,62 @@ var (
 	cmdLong = templates.LongDesc(`
 		Views the boot Job logs in the cluster
 
+		Use --output json to write newline delimited JSON events to stdout for the git operator being ready, the boot Job
+		being selected, boot Job pod status changes, each log line and the boot Job finishing so that the output can be
+		processed by other tools.
+
+		If there is more than one boot Job you are prompted to pick the Job to log. Use --job, --index, --latest-failed or
+		--latest-succeeded to choose the Job without prompting. In batch mode the latest Job is logged.
+
+		Use --since, --since-time and --tail to limit how much of each log is displayed and --timestamps to prefix each line
+		with its timestamp. Use --grep to only display the lines matching a regular expression and --context to also
+		display the lines before and after each match. The lines hidden by --grep are still used by --summary and to
+		diagnose failures.
+
+		Use --summary to analyse the boot log rather than display it. The boot phases (git clone, secret population,
+		helmfile template, apply and verification) are displayed with their status and duration. If the boot failed the
+		failing phase, release and an excerpt of the errors are displayed.
+
+		If a boot Job pod cannot pull its images for longer than --image-pull-timeout we stop waiting for the pod and
+		diagnose the failure. Image pull failures are often transient so use --image-pull-timeout 0 to keep waiting.
+
+		If the boot Job fails its log and the events of the Job and its pods are matched against rules for common failures
+		such as an expired git token, a missing Secret, a helm upgrade timeout, a CRD conflict or an image pull failure.
+		A diagnosis with a suggested fix is displayed for each matching rule. Use --rules to supply additional rules in a
+		YAML file of the form:
+
+		    rules:
+		    - name: my-rule
+		      description: describes the failure
+		      logPatterns:
+		      - "regular expression matched against each log line"
+		      eventPatterns:
+		      - "regular expression matched against the 'reason: message' of each event"
+		      fix: how to fix the failure
+		      docURL: https://example.com/docs
 `)
 
 	cmdExample = templates.Examples(`
 * views the current boot logs
 ` + bashExample("log") + `
+* views the log of the latest boot Job which failed without prompting
+` + bashExample("log --latest-failed") + `
+* views the log of the boot Job before the latest one
+` + bashExample("log --index 2") + `
+* views the logs of all the init containers and containers of the boot Job including any restarted containers
+` + bashExample("log --all-containers --previous") + `
+* views the last 100 lines of the boot log from the last 10 minutes with timestamps
+` + bashExample("log --tail 100 --since 10m --timestamps") + `
+* views the lines of the boot log mentioning a release with 3 lines of context
+` + bashExample("log --grep jx-pipelines-visualizer --context 3") + `
+* summarises the boot phases of the current boot log
+` + bashExample("log --summary") + `
+* views the current boot logs diagnosing failures with additional rules
+` + bashExample("log --rules my-rules.yaml") + `
+* writes the boot log events as newline delimited JSON
+` + bashExample("log --output json") + `
+* archives the boot Job logs into a tarball
+` + bashExample("log archive -o boot-logs.tar.gz") + `
 `)
 )
 
@@ -95,11 +171,29 @@ func NewCmdJobLog() (*cobra.Command, *Options) {
 	command.Flags().StringVarP(&o.JobSelector, "selector", "s", "app=jx-boot", "the selector of the boot Job pods")
 	command.Flags().StringVarP(&o.GitOperatorSelector, "git-operator-selector", "g", "app=jx-git-operator", "the selector of the git operator pod")
 	command.Flags().StringVarP(&o.ContainerName, "container", "c", "job", "the name of the container in the boot Job to log")
+	command.Flags().BoolVarP(&o.AllContainers, "all-containers", "", false, "log all the init containers in order and all the containers of the boot Job pods. Each line is prefixed with the pod and container name")
+	command.Flags().BoolVarP(&o.Previous, "previous", "p", false, "also log the previous terminated instance of any restarted containers before the current instance")
 	command.Flags().StringVarP(&o.CommitSHA, "commit-sha", "", "", "the git commit SHA of the git repository to query the boot Job for")
+	command.Flags().StringVarP(&o.JobName, "job", "", "", "the name of the boot Job to log")
+	command.Flags().IntVarP(&o.JobIndex, "index", "", 0, "the number of the boot Job to log starting from the latest Job which is 1")
+	command.Flags().BoolVarP(&o.LatestFailed, "latest-failed", "", false, "log the latest boot Job which failed")
+	command.Flags().BoolVarP(&o.LatestSucceeded, "latest-succeeded", "", false, "log the latest boot Job which succeeded")
 	command.Flags().BoolVarP(&o.WaitMode, "wait", "w", false, "wait for the next active Job to start")
 	command.Flags().BoolVarP(&o.ShaMode, "sha-mode", "", false, "if --commit-sha is not specified then default the git commit SHA from $ and fail if it could not be found")
 	command.Flags().DurationVarP(&o.Duration, "duration", "d", time.Minute*30, "how long to wait for a Job to be active and a Pod to be ready")
-	command.Flags().DurationVarP(&o.PollPeriod, "poll", "", time.Second*1, "duration between polls for an active Job or Pod")
+	command.Flags().DurationVarP(&o.PollPeriod, "poll", "", time.Second*1, "duration between polls for an active Job or Pod if watching is disabled or not supported")
+	command.Flags().DurationVarP(&o.ImagePullTimeout, "image-pull-timeout", "", time.Minute*5, "how long a boot Job pod can fail to pull its images before giving up and diagnosing the failure. If 0 waits for the --duration")
+	command.Flags().BoolVarP(&o.NoWatch, "no-watch", "", false, "disables watching the boot Jobs and Pods and polls them every --poll period instead")
+	command.Flags().DurationVarP(&o.Since, "since", "", 0, "only log lines newer than a relative duration such as 5m or 1h")
+	command.Flags().StringVarP(&o.SinceTime, "since-time", "", "", "only log lines after an RFC3339 time such as 2024-01-02T15:04:05Z")
+	command.Flags().Int64VarP(&o.TailLines, "tail", "", -1, "the number of lines from the end of each log to display. If -1 all lines are displayed")
+	command.Flags().BoolVarP(&o.Timestamps, "timestamps", "", false, "prefix each log line with its timestamp")
+	command.Flags().StringVarP(&o.Grep, "grep", "", "", "only log lines matching this regular expression")
+	command.Flags().IntVarP(&o.GrepContext, "context", "C", 0, "the number of lines before and after each line matching --grep to log")
+	command.Flags().BoolVarP(&o.Summary, "summary", "", false, "analyses the boot log and displays the status and duration of each boot phase instead of the log. If the boot Job failed the failing release and the error are displayed")
+	command.Flags().BoolVarP(&o.NoDiagnose, "no-diagnose", "", false, "disables diagnosing the cause of a failed boot Job")
+	command.Flags().StringArrayVarP(&o.RuleFiles, "rules", "", nil, "a YAML file of additional rules to diagnose failed boot Jobs. Rules replace any built-in rules with the same name")
+	command.Flags().StringVarP(&o.Output, "output", "o", "", fmt.Sprintf("the output format. Possible values: %s. If not specified the log is displayed as text", strings.Join(OutputFormats, ", ")))
 
 	o.BaseOptions.AddBaseFlags(command)
 
@@ -126,7 +220,7 @@ func (o *Options) Run() error {
 		return fmt.Errorf("failed to get jobs: %w", err)
 	}
 
-	if !o.WaitMode && len(sortedJobs) <= 1 {
+	if !o.WaitMode && !o.jobSelected() && len(sortedJobs) <= 1 {
 		if len(sortedJobs) == 0 {
 			o.WaitMode = true
 		} else {
@@ -165,6 +259,12 @@ See: https://jayex.io/v3/admin/setup/operator/
 		return fmt.Errorf("no git operator pod to be ready in namespace %s with selector %s: %w", ns, o.GitOperatorSelector, err)
 	}
 	logger.Logger().Infof("the Git Operator is running in pod %s\n\n", info(goPod.Name))
+	o.emit(&Event{
+		Type:      EventOperatorReady,
+		Namespace: ns,
+		Pod:       goPod.Name,
+		Status:    pods.PodStatus(goPod),
+	})
 
 	if o.CommitSHA != "" {
 		logger.Logger().Infof("waiting for boot Job pod with selector %s in namespace %s for commit SHA %s...", info(selector), info(ns), info(o.CommitSHA))
@@ -182,8 +282,18 @@ func (o *Options) waitForActiveJob(client kubernetes.Interface, ns, selector str
 	}
 
 	logger.Logger().Infof("waiting for Job %s to complete...", info(job.Name))
+	o.emitJobSelected(ns, job)
 
-	return o.viewActiveJobLog(client, ns, selector, containerName, job)
+	err = o.startDiagnosis()
+	if err != nil {
+		return err
+	}
+	o.imagePulls = newImagePullTracker()
+	err = o.viewActiveJobLog(client, ns, selector, containerName, job)
+	if err != nil {
+		o.diagnose(client, ns, job.Name)
+	}
+	return err
 }
 
 func (o *Options) viewActiveJobLog(client kubernetes.Interface, ns, selector, containerName string, job *batchv1.Job) error {
@@ -205,9 +315,11 @@ func (o *Options) viewActiveJobLog(client kubernetes.Interface, ns, selector, co
 		}
 
 		// lets verify the container name
-		err = verifyContainerName(pod, containerName)
-		if err != nil {
-			return err
+		if !o.AllContainers {
+			err = verifyContainerName(pod, containerName)
+			if err != nil {
+				return err
+			}
 		}
 		podName := pod.Name
 		if stringhelpers.StringArrayIndex(foundPods, podName) < 0 {
@@ -215,7 +327,7 @@ func (o *Options) viewActiveJobLog(client kubernetes.Interface, ns, selector, co
 		}
 		logger.Logger().Infof("\ntailing boot Job pod %s\n\n", info(podName))
 
-		err = podlogs.TailLogs(ns, podName, containerName, o.ErrOut, o.Out)
+		err = o.tailLogs(client, ns, pod, containerName)
 		if err != nil {
 			logger.Logger().Warnf("failed to tail log: %s", err.Error())
 		}
@@ -223,15 +335,8 @@ func (o *Options) viewActiveJobLog(client kubernetes.Interface, ns, selector, co
 		if err != nil {
 			return fmt.Errorf("failed to get pod %s in namespace %s: %w", podName, ns, err)
 		}
-		if pods.IsPodCompleted(pod) {
-			if pods.IsPodSucceeded(pod) {
-				logger.Logger().Infof("boot Job pod %s has %s", info(podName), info("Succeeded"))
-			} else {
-				logger.Logger().Infof("boot Job pod %s is %s", info(podName), termcolor.ColorError(string(pod.Status.Phase)))
-			}
-		} else if pod.DeletionTimestamp != nil {
-			logger.Logger().Infof("boot Job pod %s is %s", info(podName), termcolor.ColorWarning("Terminating"))
-		}
+		o.logPodComplete(pod)
+		o.summarise(pod)
 	}
 }
 
@@ -258,9 +363,11 @@ func (o *Options) viewJobLog(client kubernetes.Interface, ns, selector, containe
 		pod := &pos[i]
 
 		// lets verify the container name
-		err = verifyContainerName(pod, containerName)
-		if err != nil {
-			return err
+		if !o.AllContainers {
+			err = verifyContainerName(pod, containerName)
+			if err != nil {
+				return err
+			}
 		}
 
 		// wait for a pod to be running, ready or completed
@@ -274,7 +381,7 @@ func (o *Options) viewJobLog(client kubernetes.Interface, ns, selector, containe
 		podName := pod.Name
 		logger.Logger().Infof("\ntailing boot Job pod %s created %s\n\n", info(podName), info(pod.CreationTimestamp))
 
-		err = podlogs.TailLogs(ns, podName, containerName, o.ErrOut, o.Out)
+		err = o.tailLogs(client, ns, pod, containerName)
 		if err != nil {
 			logger.Logger().Warnf("failed to tail log: %s", err.Error())
 		}
@@ -282,15 +389,8 @@ func (o *Options) viewJobLog(client kubernetes.Interface, ns, selector, containe
 		if err != nil {
 			return fmt.Errorf("failed to get pod %s in namespace %s: %w", podName, ns, err)
 		}
-		if pods.IsPodCompleted(pod) {
-			if pods.IsPodSucceeded(pod) {
-				logger.Logger().Infof("boot Job pod %s has %s", info(podName), info("Succeeded"))
-			} else {
-				logger.Logger().Infof("boot Job pod %s has %s", info(podName), termcolor.ColorError(string(pod.Status.Phase)))
-			}
-		} else if pod.DeletionTimestamp != nil {
-			logger.Logger().Infof("boot Job pod %s is %s", info(podName), termcolor.ColorWarning("Terminating"))
-		}
+		o.logPodComplete(pod)
+		o.summarise(pod)
 		lastPod = pod
 	}
 	// If job is active return error if latest pod has failed
@@ -298,6 +398,9 @@ func (o *Options) viewJobLog(client kubernetes.Interface, ns, selector, containe
 	if err != nil {
 		return fmt.Errorf("failed to get boot Job %s: %w", jobName, err)
 	}
+	if jobs.IsJobFinished(job) {
+		o.emitJobFinished(job)
+	}
 	if !jobs.IsJobFinished(job) && lastPod != nil {
 		if !pods.IsPodSucceeded(lastPod) {
 			return fmt.Errorf("boot Job pod %s has %s", lastPod.Name, string(lastPod.Status.Phase))
@@ -323,6 +426,24 @@ func (o *Options) Validate() error {
 	if o.Out == nil {
 		o.Out = os.Stdout
 	}
+	if o.Output != "" && !o.JSONOutput() {
+		return options.InvalidOption("output", o.Output, OutputFormats)
+	}
+	if o.ImagePullTimeout < 0 {
+		return options.InvalidOptionf("image-pull-timeout", o.ImagePullTimeout, "must not be negative")
+	}
+	err := o.validateJobSelection()
+	if err != nil {
+		return err
+	}
+	err = o.validateFilters()
+	if err != nil {
+		return err
+	}
+	err = o.loadRules()
+	if err != nil {
+		return err
+	}
 	if o.ShaMode && o.CommitSHA == "" {
 		o.CommitSHA = os.Getenv("PULL_BASE_SHA")
 		if o.ShaMode && o.CommitSHA == "" {
@@ -330,13 +451,12 @@ func (o *Options) Validate() error {
 		}
 	}
 
-	var err error
-	o.KubeClient, err = kube.LazyCreateKubeClientWithMandatory(o.KubeClient, true)
+	o.KubeClient, err = o.KubeConfig.LazyCreateKubeClient(o.KubeClient)
 	if err != nil {
 		return fmt.Errorf("failed to create kubernetes client: %w", err)
 	}
 	if o.Namespace == "" {
-		o.Namespace, err = kubeclient.CurrentNamespace()
+		o.Namespace, err = o.KubeConfig.CurrentNamespace()
 		if err != nil {
 			return fmt.Errorf("failed to detect current namespace. Try supply --namespace: %w", err)
 		}
@@ -347,7 +467,89 @@ func (o *Options) Validate() error {
 	return nil
 }
 
+// validateJobSelection validates the options which choose the boot Job to log without prompting
+func (o *Options) validateJobSelection() error {
+	if o.JobIndex < 0 {
+		return options.InvalidOptionf("index", o.JobIndex, "must be 1 or more")
+	}
+	var flags []string
+	if o.JobName != "" {
+		flags = append(flags, "--job")
+	}
+	if o.JobIndex > 0 {
+		flags = append(flags, "--index")
+	}
+	if o.LatestFailed {
+		flags = append(flags, "--latest-failed")
+	}
+	if o.LatestSucceeded {
+		flags = append(flags, "--latest-succeeded")
+	}
+	if len(flags) > 1 {
+		return fmt.Errorf("only one of %s can be specified", strings.Join(flags, ", "))
+	}
+	if len(flags) == 1 && o.WaitMode {
+		return fmt.Errorf("%s cannot be used with --wait", flags[0])
+	}
+	return nil
+}
+
+// jobSelected returns true if the boot Job to log has been chosen via the command line options
+func (o *Options) jobSelected() bool {
+	return o.JobName != "" || o.JobIndex > 0 || o.LatestFailed || o.LatestSucceeded
+}
+
+// validateFilters validates the options which filter the log lines
+func (o *Options) validateFilters() error {
+	if o.Since < 0 {
+		return options.InvalidOptionf("since", o.Since, "must not be negative")
+	}
+	if o.SinceTime != "" {
+		if o.Since > 0 {
+			return options.InvalidOptionf("since-time", o.SinceTime, "cannot be used with --since")
+		}
+		t, err := time.Parse(time.RFC3339, o.SinceTime)
+		if err != nil {
+			return options.InvalidOptionf("since-time", o.SinceTime, "must be an RFC3339 time such as 2024-01-02T15:04:05Z: %s", err.Error())
+		}
+		since := metav1.NewTime(t)
+		o.sinceTime = &since
+	}
+	if o.TailLines < -1 {
+		return options.InvalidOptionf("tail", o.TailLines, "must be -1 or more")
+	}
+	if o.GrepContext < 0 {
+		return options.InvalidOptionf("context", o.GrepContext, "must not be negative")
+	}
+	if o.Grep == "" {
+		if o.GrepContext > 0 {
+			return options.InvalidOptionf("context", o.GrepContext, "can only be used with --grep")
+		}
+		return nil
+	}
+	var err error
+	o.grepRegex, err = regexp.Compile(o.Grep)
+	if err != nil {
+		return options.InvalidOptionf("grep", o.Grep, "must be a valid regular expression: %s", err.Error())
+	}
+	return nil
+}
+
+// waitForLatestJob watches the boot Jobs until the latest Job for the commit SHA or the latest active Job is found
+// falling back to polling if we cannot watch Jobs
 func (o *Options) waitForLatestJob(client kubernetes.Interface, ns, selector string) (*batchv1.Job, error) {
+	if !o.NoWatch {
+		job, err := o.watchForLatestJob(client, ns, selector)
+		if !errors.Is(err, errWatchNotSupported) {
+			return job, err
+		}
+		logger.Logger().Warnf("falling back to polling for the boot Jobs: %s", err.Error())
+		o.NoWatch = true
+	}
+	return o.pollForLatestJob(client, ns, selector)
+}
+
+func (o *Options) pollForLatestJob(client kubernetes.Interface, ns, selector string) (*batchv1.Job, error) {
 	for {
 		job, err := o.getLatestJob(client, ns, selector)
 		if err != nil {
@@ -367,7 +569,24 @@ func (o *Options) waitForLatestJob(client kubernetes.Interface, ns, selector str
 	}
 }
 
+// waitForJobCompleteOrPodRunning watches the boot Job and its Pods until either the Job completes or a Pod is running
+// falling back to polling if we cannot watch Jobs or Pods
 func (o *Options) waitForJobCompleteOrPodRunning(client kubernetes.Interface, ns, selector, jobName string) (bool, *corev1.Pod, error) {
+	if o.imagePulls == nil {
+		o.imagePulls = newImagePullTracker()
+	}
+	if !o.NoWatch {
+		complete, pod, err := o.watchJobCompleteOrPodRunning(client, ns, selector, jobName)
+		if !errors.Is(err, errWatchNotSupported) {
+			return complete, pod, err
+		}
+		logger.Logger().Warnf("falling back to polling for the boot Job and Pods: %s", err.Error())
+		o.NoWatch = true
+	}
+	return o.pollForJobCompleteOrPodRunning(client, ns, selector, jobName)
+}
+
+func (o *Options) pollForJobCompleteOrPodRunning(client kubernetes.Interface, ns, selector, jobName string) (bool, *corev1.Pod, error) {
 	if o.podStatusMap == nil {
 		o.podStatusMap = map[string]string{}
 	}
@@ -384,16 +603,17 @@ func (o *Options) waitForJobCompleteOrPodRunning(client kubernetes.Interface, ns
 			return true, nil, nil
 		}
 
+		err = o.checkImagePull(client, ns, jobName)
+		if err != nil {
+			return false, nil, err
+		}
+
 		pod, err := pods.GetReadyPodForSelector(client, ns, selector)
 		if err != nil {
 			return false, pod, fmt.Errorf("failed to query ready pod in namespace %s with selector %s: %w", ns, selector, err)
 		}
 		if pod != nil {
-			status := pods.PodStatus(pod)
-			if o.podStatusMap[pod.Name] != status && !pods.IsPodCompleted(pod) && pod.DeletionTimestamp == nil {
-				logger.Logger().Infof("pod %s has status %s", termcolor.ColorInfo(pod.Name), termcolor.ColorInfo(status))
-				o.podStatusMap[pod.Name] = status
-			}
+			o.logPodStatus(pod)
 			if pod.Status.Phase == v1.PodRunning || pods.IsPodReady(pod) {
 				return false, pod, nil
 			}
@@ -406,6 +626,31 @@ func (o *Options) waitForJobCompleteOrPodRunning(client kubernetes.Interface, ns
 	}
 }
 
+// checkImagePull records the pods of the Job which cannot pull their images and returns an error if a container
+// has been failing to pull its image for longer than the image pull timeout
+func (o *Options) checkImagePull(client kubernetes.Interface, ns, jobName string) error {
+	podList, err := client.CoreV1().Pods(ns).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: "job-name=" + jobName,
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to list the pods of Job %s in namespace %s: %w", jobName, ns, err)
+	}
+	podNames := map[string]bool{}
+	if podList != nil {
+		for i := range podList.Items {
+			pod := &podList.Items[i]
+			podNames[pod.Name] = true
+			o.imagePulls.update(pod)
+		}
+	}
+	for _, f := range o.imagePulls.list() {
+		if !podNames[f.pod] {
+			o.imagePulls.remove(f.pod)
+		}
+	}
+	return o.imagePulls.check(o.ImagePullTimeout)
+}
+
 func (o *Options) getLatestJob(client kubernetes.Interface, ns, selector string) (*batchv1.Job, error) {
 	jobList, err := client.BatchV1().Jobs(ns).List(context.TODO(), metav1.ListOptions{
 		LabelSelector: selector,
@@ -413,32 +658,37 @@ func (o *Options) getLatestJob(client kubernetes.Interface, ns, selector string)
 	if err != nil && !apierrors.IsNotFound(err) {
 		return nil, fmt.Errorf("failed to list jobList in namespace %s selector %s: %w", ns, selector, err)
 	}
-	if len(jobList.Items) == 0 {
-		return nil, nil
+	return o.findLatestJob(jobList.Items), nil
+}
+
+// findLatestJob returns the Job for the commit SHA if specified or the newest Job
+func (o *Options) findLatestJob(jobList []batchv1.Job) *batchv1.Job {
+	if len(jobList) == 0 {
+		return nil
 	}
 
 	if o.CommitSHA != "" {
-		for i := 0; i < len(jobList.Items); i++ {
-			job := &jobList.Items[i]
+		for i := 0; i < len(jobList); i++ {
+			job := &jobList[i]
 			labels := job.Labels
 			if labels != nil {
 				if o.CommitSHA == labels[bootjobs.LabelCommitSHA] {
-					return job, nil
+					return job
 				}
 			}
 		}
-		return nil, nil
+		return nil
 	}
 
 	// lets find the newest job...
-	latest := jobList.Items[0]
-	for i := 1; i < len(jobList.Items); i++ {
-		job := jobList.Items[i]
+	latest := jobList[0]
+	for i := 1; i < len(jobList); i++ {
+		job := jobList[i]
 		if job.CreationTimestamp.After(latest.CreationTimestamp.Time) {
 			latest = job
 		}
 	}
-	return &latest, nil
+	return &latest
 }
 
 func (o *Options) checkIfJobComplete(client kubernetes.Interface, ns, name string) (bool, *batchv1.Job, error) {
@@ -447,11 +697,7 @@ func (o *Options) checkIfJobComplete(client kubernetes.Interface, ns, name strin
 		return false, nil, fmt.Errorf("failed to list jobList in namespace %s name %s: %w", ns, name, err)
 	}
 	if jobs.IsJobFinished(job) {
-		if jobs.IsJobSucceeded(job) {
-			logger.Logger().Infof("boot Job %s has %s", info(job.Name), info("Succeeded"))
-			return true, job, nil
-		}
-		logger.Logger().Infof("boot Job %s has %s", info(job.Name), termcolor.ColorError("Failed"))
+		o.logJobComplete(job)
 		return true, job, nil
 	}
 	logger.Logger().Debugf("boot Job %s is not completed yet", info(job.Name))
@@ -459,27 +705,82 @@ func (o *Options) checkIfJobComplete(client kubernetes.Interface, ns, name strin
 }
 
 func (o *Options) pickJobToLog(client kubernetes.Interface, ns, selector string, jobs []batchv1.Job) error {
+	job, err := o.selectJob(jobs)
+	if err != nil {
+		return err
+	}
+	o.emitJobSelected(ns, job)
+	err = o.startDiagnosis()
+	if err != nil {
+		return err
+	}
+	err = o.viewJobLog(client, ns, selector, o.ContainerName, job)
+	if err != nil {
+		o.diagnose(client, ns, job.Name)
+	}
+	return err
+}
+
+// selectJob returns the boot Job chosen via the command line options, the latest Job in batch mode or
+// prompts the user to pick the Job. The jobs are sorted with the latest first
+func (o *Options) selectJob(jobs []batchv1.Job) (*batchv1.Job, error) {
+	switch {
+	case o.JobName != "":
+		for i := range jobs {
+			if jobs[i].Name == o.JobName {
+				return &jobs[i], nil
+			}
+		}
+		return nil, options.InvalidOptionf("job", o.JobName, "there is no boot Job with that name")
+	case o.JobIndex > 0:
+		if o.JobIndex > len(jobs) {
+			return nil, options.InvalidOptionf("index", o.JobIndex, "there are only %d boot Jobs", len(jobs))
+		}
+		return &jobs[o.JobIndex-1], nil
+	case o.LatestFailed:
+		return findJobWithStatus(jobs, "Failed")
+	case o.LatestSucceeded:
+		return findJobWithStatus(jobs, "Succeeded")
+	}
+
+	if len(jobs) == 0 {
+		return nil, fmt.Errorf("no boot Jobs to view. Try add --wait to wait for the next boot job")
+	}
+	if o.BatchMode {
+		return &jobs[0], nil
+	}
+
 	var names []string
 	m := map[string]*batchv1.Job{}
 	for i := range jobs {
 		j := &jobs[i]
-		name := toJobName(j, len(jobs)-i)
+		name := toJobName(j, i+1)
 		m[name] = j
 		names = append(names, name)
 	}
 
 	name, err := o.Input.PickNameWithDefault(names, "select the Job to view:", "", "select which boot Job you wish to log")
 	if err != nil {
-		return fmt.Errorf("failed to pick a boot job name: %w", err)
+		return nil, fmt.Errorf("failed to pick a boot job name: %w", err)
 	}
 	if name == "" {
-		return fmt.Errorf("no boot Jobs to view. Try add --active to wait for the next boot job")
+		return nil, fmt.Errorf("no boot Jobs to view. Try add --wait to wait for the next boot job")
 	}
 	job := m[name]
 	if job == nil {
-		return fmt.Errorf("cannot find Job %s", name)
+		return nil, fmt.Errorf("cannot find Job %s", name)
+	}
+	return job, nil
+}
+
+// findJobWithStatus returns the latest boot Job with the status
+func findJobWithStatus(jobs []batchv1.Job, status string) (*batchv1.Job, error) {
+	for i := range jobs {
+		if JobStatus(&jobs[i]) == status {
+			return &jobs[i], nil
+		}
 	}
-	return o.viewJobLog(client, ns, selector, o.ContainerName, job)
+	return nil, fmt.Errorf("there is no boot Job which has %s", strings.ToLower(status))
 }
 
 func toJobName(j *batchv1.Job, number int) string {
@@ -507,6 +808,12 @@ func JobStatus(j *batchv1.Job) string {
 
 func verifyContainerName(pod *corev1.Pod, name string) error {
 	var names []string
+	for i := range pod.Spec.InitContainers {
+		if pod.Spec.InitContainers[i].Name == name {
+			return nil
+		}
+		names = append(names, pod.Spec.InitContainers[i].Name)
+	}
 	for i := range pod.Spec.Containers {
 		if pod.Spec.Containers[i].Name == name {
 			return nil
@@ -0,0 +1,713 @@
+package joblog_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/bootjobs"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/cmd/joblog"
+	fakeinput "github.com/jenkins-x/jx-helpers/v3/pkg/input/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const ns = "jx-git-operator"
+
+func TestJobLogWatchesActiveJob(t *testing.T) {
+	kubeClient := newFakeKubeClient()
+	watching := watchingJob(kubeClient)
+
+	_, o := joblog.NewCmdJobLog()
+	o.KubeClient = kubeClient
+	o.Namespace = ns
+	o.Duration = time.Minute
+	// if we polled the test would time out so this verifies we watch the Job
+	o.PollPeriod = time.Hour
+	o.Output = "json"
+	out := &bytes.Buffer{}
+	o.Out = out
+
+	go completeJob(t, kubeClient, watching, batchv1.JobComplete)
+
+	start := time.Now()
+	err := o.Run()
+	require.NoError(t, err, "failed to wait for the boot Job")
+	assert.Less(t, time.Since(start), o.Duration, "should have completed before the timeout")
+	assert.False(t, o.NoWatch, "should not have fallen back to polling")
+
+	var eventTypes []string
+	decoder := json.NewDecoder(out)
+	for decoder.More() {
+		event := &joblog.Event{}
+		err = decoder.Decode(event)
+		require.NoError(t, err, "failed to parse event JSON in output:\n%s", out.String())
+		assert.False(t, event.Time.IsZero(), "event %s should have a time", event.Type)
+		eventTypes = append(eventTypes, event.Type)
+
+		if event.Type == joblog.EventJobFinished {
+			assert.Equal(t, "jx-boot-abc", event.Job, "job of %s event", event.Type)
+			assert.Equal(t, "Succeeded", event.Result, "result of %s event", event.Type)
+		}
+	}
+	assert.Equal(t, []string{joblog.EventOperatorReady, joblog.EventJobSelected, joblog.EventJobFinished}, eventTypes, "event types")
+}
+
+func TestJobLogFallsBackToPolling(t *testing.T) {
+	kubeClient := newFakeKubeClient()
+	kubeClient.PrependWatchReactor("jobs", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Group: "batch", Resource: "jobs"}, "", nil)
+	})
+
+	// lets complete the Job once we are polling it
+	polling := make(chan struct{})
+	var once sync.Once
+	kubeClient.PrependReactor("get", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		once.Do(func() { close(polling) })
+		return false, nil, nil
+	})
+
+	_, o := joblog.NewCmdJobLog()
+	o.KubeClient = kubeClient
+	o.Namespace = ns
+	o.Duration = time.Minute
+	o.PollPeriod = time.Millisecond * 10
+
+	go completeJob(t, kubeClient, polling, batchv1.JobComplete)
+
+	err := o.Run()
+	require.NoError(t, err, "failed to wait for the boot Job")
+	assert.True(t, o.NoWatch, "should have fallen back to polling")
+}
+
+func TestJobLogDiagnosesFailedJob(t *testing.T) {
+	kubeClient := newFakeKubeClient(
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "jx-boot-abc-1",
+				Namespace: ns,
+				Labels: map[string]string{
+					"app":      "jx-boot",
+					"job-name": "jx-boot-abc",
+				},
+			},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodPending,
+			},
+		},
+		&corev1.Event{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "jx-boot-abc-1.1",
+				Namespace: ns,
+			},
+			InvolvedObject: corev1.ObjectReference{
+				Kind: "Pod",
+				Name: "jx-boot-abc-1",
+			},
+			Reason:  "Failed",
+			Message: `Failed to pull image "ghcr.io/jenkins-x/jx-boot:0.0.0": rpc error: code = NotFound`,
+		},
+	)
+	watching := watchingJob(kubeClient)
+
+	_, o := joblog.NewCmdJobLog()
+	o.KubeClient = kubeClient
+	o.Namespace = ns
+	o.Duration = time.Minute
+	out := &bytes.Buffer{}
+	o.Out = out
+
+	go completeJob(t, kubeClient, watching, batchv1.JobFailed)
+
+	err := o.Run()
+	require.Error(t, err, "the boot Job should have failed")
+	t.Logf("output:\n%s\n", out.String())
+	assert.Contains(t, out.String(), "image-pull-backoff", "diagnosis")
+	assert.Contains(t, out.String(), "Failed to pull image", "diagnosis evidence")
+}
+
+func TestJobLogImagePullTimeout(t *testing.T) {
+	testCases := []struct {
+		noWatch          bool
+		imagePullTimeout time.Duration
+		expectError      string
+	}{
+		{
+			imagePullTimeout: time.Millisecond * 50,
+			expectError:      "ImagePullBackOff",
+		},
+		{
+			noWatch:          true,
+			imagePullTimeout: time.Millisecond * 50,
+			expectError:      "ImagePullBackOff",
+		},
+		{
+			expectError: "timed out",
+		},
+		{
+			noWatch:     true,
+			expectError: "timed out",
+		},
+	}
+
+	for _, tc := range testCases {
+		noWatch := tc.noWatch
+		kubeClient := newFakeKubeClient(
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "jx-boot-abc-1",
+					Namespace: ns,
+					Labels: map[string]string{
+						"app":      "jx-boot",
+						"job-name": "jx-boot-abc",
+					},
+				},
+				Status: corev1.PodStatus{
+					Phase: corev1.PodPending,
+					InitContainerStatuses: []corev1.ContainerStatus{
+						{
+							Name:  "git-clone",
+							Image: "ghcr.io/jenkins-x/jx-boot:0.0.0",
+							State: corev1.ContainerState{
+								Waiting: &corev1.ContainerStateWaiting{
+									Reason:  "ImagePullBackOff",
+									Message: `Back-off pulling image "ghcr.io/jenkins-x/jx-boot:0.0.0"`,
+								},
+							},
+						},
+					},
+				},
+			},
+		)
+
+		_, o := joblog.NewCmdJobLog()
+		o.KubeClient = kubeClient
+		o.Namespace = ns
+		o.Duration = time.Minute
+		if tc.imagePullTimeout == 0 {
+			// the image pull failure may be transient so we wait until the duration times out
+			o.Duration = time.Millisecond * 300
+		}
+		o.ImagePullTimeout = tc.imagePullTimeout
+		o.PollPeriod = time.Millisecond * 10
+		o.NoWatch = noWatch
+		out := &bytes.Buffer{}
+		o.Out = out
+
+		start := time.Now()
+		err := o.Run()
+		require.Error(t, err, "should fail as the image cannot be pulled when noWatch is %v", noWatch)
+		if tc.imagePullTimeout > 0 {
+			assert.Less(t, time.Since(start), o.Duration, "should have failed before the timeout when noWatch is %v", noWatch)
+		}
+		assert.Contains(t, err.Error(), tc.expectError, "error when noWatch is %v and image pull timeout is %s", noWatch, tc.imagePullTimeout)
+		assert.Contains(t, out.String(), "image-pull-backoff", "diagnosis when noWatch is %v and image pull timeout is %s", noWatch, tc.imagePullTimeout)
+	}
+}
+
+func TestJobLogAllContainers(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      bootjobs.GitOperatorDeploymentName,
+				Namespace: ns,
+			},
+		},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "jx-boot-abc",
+				Namespace:         ns,
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-10 * time.Minute)),
+				Labels: map[string]string{
+					"app": "jx-boot",
+				},
+			},
+			Status: batchv1.JobStatus{
+				Succeeded: 1,
+				Conditions: []batchv1.JobCondition{
+					{
+						Type:   batchv1.JobComplete,
+						Status: corev1.ConditionTrue,
+					},
+				},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "jx-boot-abc-1",
+				Namespace: ns,
+				Labels: map[string]string{
+					"app":      "jx-boot",
+					"job-name": "jx-boot-abc",
+				},
+			},
+			Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{
+					{
+						Name: "git-clone",
+					},
+				},
+				Containers: []corev1.Container{
+					{
+						Name: "job",
+					},
+					{
+						Name: "proxy",
+					},
+				},
+			},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodSucceeded,
+				ContainerStatuses: []corev1.ContainerStatus{
+					{
+						Name:         "job",
+						RestartCount: 1,
+					},
+				},
+			},
+		},
+	)
+	kubeClient.PrependReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "log" {
+			return false, nil, nil
+		}
+		opts := action.(k8stesting.GenericAction).GetValue().(*corev1.PodLogOptions)
+		text := opts.Container + " log"
+		if opts.Previous {
+			text = opts.Container + " previous log"
+		}
+		return true, &runtime.Unknown{Raw: []byte(text + "\n")}, nil
+	})
+
+	_, o := joblog.NewCmdJobLog()
+	o.KubeClient = kubeClient
+	o.Namespace = ns
+	o.AllContainers = true
+	o.Previous = true
+	o.Input = &fakeinput.FakeInput{OrderedValues: []string{"#1 started 10m0s Succeeded"}}
+	out := &bytes.Buffer{}
+	o.Out = out
+
+	err := o.Run()
+	require.NoError(t, err, "failed to view the boot Job log")
+
+	text := out.String()
+	t.Logf("output:\n%s\n", text)
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	require.Len(t, lines, 4, "log lines")
+	assert.Contains(t, lines[0], "[jx-boot-abc-1/git-clone]", "first line prefix")
+	assert.Contains(t, lines[0], "git-clone log", "first line")
+	assert.Contains(t, lines[1], "[jx-boot-abc-1/job (previous)]", "second line prefix")
+	assert.Contains(t, lines[1], "job previous log", "second line")
+	assert.Contains(t, text, "[jx-boot-abc-1/job]", "job container prefix")
+	assert.Contains(t, text, "job log", "job container log")
+	assert.Contains(t, text, "[jx-boot-abc-1/proxy]", "proxy container prefix")
+	assert.Contains(t, text, "proxy log", "proxy container log")
+}
+
+func TestJobLogGrepWithContext(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      bootjobs.GitOperatorDeploymentName,
+				Namespace: ns,
+			},
+		},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "jx-boot-abc",
+				Namespace:         ns,
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-10 * time.Minute)),
+				Labels: map[string]string{
+					"app": "jx-boot",
+				},
+			},
+			Status: batchv1.JobStatus{
+				Succeeded: 1,
+				Conditions: []batchv1.JobCondition{
+					{
+						Type:   batchv1.JobComplete,
+						Status: corev1.ConditionTrue,
+					},
+				},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "jx-boot-abc-1",
+				Namespace: ns,
+				Labels: map[string]string{
+					"app":      "jx-boot",
+					"job-name": "jx-boot-abc",
+				},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name: "job",
+					},
+				},
+			},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodSucceeded,
+			},
+		},
+	)
+	var logOptions *corev1.PodLogOptions
+	kubeClient.PrependReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "log" {
+			return false, nil, nil
+		}
+		logOptions = action.(k8stesting.GenericAction).GetValue().(*corev1.PodLogOptions)
+		var buf strings.Builder
+		for i := 1; i <= 10; i++ {
+			text := fmt.Sprintf("line %d", i)
+			if i == 3 || i == 8 {
+				text = fmt.Sprintf("line %d release=nginx", i)
+			}
+			fmt.Fprintf(&buf, "2024-01-02T15:04:%02dZ %s\n", i, text)
+		}
+		return true, &runtime.Unknown{Raw: []byte(buf.String())}, nil
+	})
+
+	_, o := joblog.NewCmdJobLog()
+	o.KubeClient = kubeClient
+	o.Namespace = ns
+	o.Since = time.Hour
+	o.TailLines = 10
+	o.Timestamps = true
+	o.Grep = "release=nginx"
+	o.GrepContext = 1
+	o.Input = &fakeinput.FakeInput{OrderedValues: []string{"#1 started 10m0s Succeeded"}}
+	out := &bytes.Buffer{}
+	o.Out = out
+
+	err := o.Run()
+	require.NoError(t, err, "failed to view the boot Job log")
+
+	require.NotNil(t, logOptions, "should have requested the log")
+	require.NotNil(t, logOptions.SinceSeconds, "since seconds")
+	assert.Equal(t, int64(3600), *logOptions.SinceSeconds, "since seconds")
+	require.NotNil(t, logOptions.TailLines, "tail lines")
+	assert.Equal(t, int64(10), *logOptions.TailLines, "tail lines")
+	assert.True(t, logOptions.Timestamps, "timestamps")
+
+	text := out.String()
+	t.Logf("output:\n%s\n", text)
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	expected := []string{
+		"2024-01-02T15:04:02Z line 2",
+		"2024-01-02T15:04:03Z line 3 release=nginx",
+		"2024-01-02T15:04:04Z line 4",
+		"--",
+		"2024-01-02T15:04:07Z line 7",
+		"2024-01-02T15:04:08Z line 8 release=nginx",
+		"2024-01-02T15:04:09Z line 9",
+	}
+	assert.Equal(t, expected, lines, "grep output")
+}
+
+func TestJobLogInvalidFilters(t *testing.T) {
+	testCases := []struct {
+		name  string
+		setup func(o *joblog.Options)
+	}{
+		{
+			name: "since and since-time",
+			setup: func(o *joblog.Options) {
+				o.Since = time.Hour
+				o.SinceTime = "2024-01-02T15:04:05Z"
+			},
+		},
+		{
+			name: "invalid since-time",
+			setup: func(o *joblog.Options) {
+				o.SinceTime = "yesterday"
+			},
+		},
+		{
+			name: "invalid tail",
+			setup: func(o *joblog.Options) {
+				o.TailLines = -2
+			},
+		},
+		{
+			name: "context without grep",
+			setup: func(o *joblog.Options) {
+				o.GrepContext = 2
+			},
+		},
+		{
+			name: "invalid grep",
+			setup: func(o *joblog.Options) {
+				o.Grep = "release=("
+			},
+		},
+	}
+	for _, tc := range testCases {
+		_, o := joblog.NewCmdJobLog()
+		o.KubeClient = newFakeKubeClient()
+		o.Namespace = ns
+		tc.setup(o)
+
+		err := o.Validate()
+		assert.Error(t, err, "should fail to validate for %s", tc.name)
+	}
+}
+
+func TestJobLogSelectsJobWithoutPrompting(t *testing.T) {
+	newJob := func(name string, age time.Duration, conditionType batchv1.JobConditionType) *batchv1.Job {
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         ns,
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+				Labels: map[string]string{
+					"app": "jx-boot",
+				},
+			},
+			Status: batchv1.JobStatus{
+				Conditions: []batchv1.JobCondition{
+					{
+						Type:    conditionType,
+						Status:  corev1.ConditionTrue,
+						Message: "boot failed",
+					},
+				},
+			},
+		}
+		if conditionType == batchv1.JobComplete {
+			job.Status.Succeeded = 1
+		}
+		return job
+	}
+
+	testCases := []struct {
+		name        string
+		setup       func(o *joblog.Options)
+		expectedJob string
+		expectError bool
+	}{
+		{
+			name: "batch mode",
+			setup: func(o *joblog.Options) {
+				o.BatchMode = true
+			},
+			expectedJob: "jx-boot-3",
+		},
+		{
+			name: "job",
+			setup: func(o *joblog.Options) {
+				o.JobName = "jx-boot-1"
+			},
+			expectedJob: "jx-boot-1",
+		},
+		{
+			name: "index",
+			setup: func(o *joblog.Options) {
+				o.JobIndex = 2
+			},
+			expectedJob: "jx-boot-2",
+			expectError: true,
+		},
+		{
+			name: "prompt numbered like index",
+			setup: func(o *joblog.Options) {
+				o.Input = &fakeinput.FakeInput{OrderedValues: []string{"#2 started 2h0m0s Failed"}}
+			},
+			expectedJob: "jx-boot-2",
+			expectError: true,
+		},
+		{
+			name: "latest failed",
+			setup: func(o *joblog.Options) {
+				o.LatestFailed = true
+			},
+			expectedJob: "jx-boot-2",
+			expectError: true,
+		},
+		{
+			name: "latest succeeded",
+			setup: func(o *joblog.Options) {
+				o.LatestSucceeded = true
+			},
+			expectedJob: "jx-boot-3",
+		},
+	}
+	for _, tc := range testCases {
+		kubeClient := fake.NewSimpleClientset(
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      bootjobs.GitOperatorDeploymentName,
+					Namespace: ns,
+				},
+			},
+			newJob("jx-boot-1", 3*time.Hour, batchv1.JobComplete),
+			newJob("jx-boot-2", 2*time.Hour, batchv1.JobFailed),
+			newJob("jx-boot-3", time.Hour, batchv1.JobComplete),
+		)
+
+		_, o := joblog.NewCmdJobLog()
+		o.KubeClient = kubeClient
+		o.Namespace = ns
+		o.Output = "json"
+		o.NoDiagnose = true
+		// any prompt fails the test as there are no values to pick
+		o.Input = &fakeinput.FakeInput{}
+		out := &bytes.Buffer{}
+		o.Out = out
+		tc.setup(o)
+
+		err := o.Run()
+		if tc.expectError {
+			require.Error(t, err, "should have failed to view the failed boot Job for %s", tc.name)
+		} else {
+			require.NoError(t, err, "failed to view the boot Job for %s", tc.name)
+		}
+
+		var selected []string
+		decoder := json.NewDecoder(out)
+		for decoder.More() {
+			event := &joblog.Event{}
+			err = decoder.Decode(event)
+			require.NoError(t, err, "failed to parse event JSON in output:\n%s", out.String())
+			if event.Type == joblog.EventJobSelected {
+				selected = append(selected, event.Job)
+			}
+		}
+		assert.Equal(t, []string{tc.expectedJob}, selected, "selected job for %s", tc.name)
+	}
+}
+
+func TestJobLogInvalidJobSelection(t *testing.T) {
+	testCases := []struct {
+		name  string
+		setup func(o *joblog.Options)
+	}{
+		{
+			name: "more than one selector",
+			setup: func(o *joblog.Options) {
+				o.LatestFailed = true
+				o.JobIndex = 1
+			},
+		},
+		{
+			name: "selector with wait",
+			setup: func(o *joblog.Options) {
+				o.JobName = "jx-boot-abc"
+				o.WaitMode = true
+			},
+		},
+		{
+			name: "negative index",
+			setup: func(o *joblog.Options) {
+				o.JobIndex = -1
+			},
+		},
+	}
+	for _, tc := range testCases {
+		_, o := joblog.NewCmdJobLog()
+		o.KubeClient = newFakeKubeClient()
+		o.Namespace = ns
+		tc.setup(o)
+
+		err := o.Validate()
+		assert.Error(t, err, "should fail to validate for %s", tc.name)
+	}
+}
+
+func newFakeKubeClient(extraObjects ...runtime.Object) *fake.Clientset {
+	objects := []runtime.Object{
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      bootjobs.GitOperatorDeploymentName,
+				Namespace: ns,
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "jx-git-operator-abc",
+				Namespace: ns,
+				Labels: map[string]string{
+					"app": "jx-git-operator",
+				},
+			},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				Conditions: []corev1.PodCondition{
+					{
+						Type:   corev1.PodReady,
+						Status: corev1.ConditionTrue,
+					},
+				},
+			},
+		},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "jx-boot-abc",
+				Namespace: ns,
+				Labels: map[string]string{
+					"app": "jx-boot",
+				},
+			},
+			Status: batchv1.JobStatus{
+				Active: 1,
+			},
+		},
+	}
+	objects = append(objects, extraObjects...)
+	return fake.NewSimpleClientset(objects...)
+}
+
+// watchingJob returns a channel which is closed when the boot Job is being watched
+func watchingJob(kubeClient *fake.Clientset) <-chan struct{} {
+	watching := make(chan struct{})
+	var once sync.Once
+	kubeClient.PrependWatchReactor("jobs", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		fieldSelector := action.(k8stesting.WatchAction).GetWatchRestrictions().Fields
+		if fieldSelector != nil && !fieldSelector.Empty() {
+			once.Do(func() { close(watching) })
+		}
+		return false, nil, nil
+	})
+	return watching
+}
+
+// completeJob marks the boot Job as finished with the condition when the channel is closed
+func completeJob(t *testing.T, kubeClient *fake.Clientset, ready <-chan struct{}, conditionType batchv1.JobConditionType) {
+	<-ready
+
+	ctx := context.TODO()
+	jobInterface := kubeClient.BatchV1().Jobs(ns)
+	job, err := jobInterface.Get(ctx, "jx-boot-abc", metav1.GetOptions{})
+	if !assert.NoError(t, err, "failed to get the boot Job") {
+		return
+	}
+	job.Status.Active = 0
+	if conditionType == batchv1.JobComplete {
+		job.Status.Succeeded = 1
+	} else {
+		job.Status.Failed = 1
+	}
+	job.Status.Conditions = append(job.Status.Conditions, batchv1.JobCondition{
+		Type:   conditionType,
+		Status: corev1.ConditionTrue,
+	})
+	_, err = jobInterface.Update(ctx, job, metav1.UpdateOptions{})
+	assert.NoError(t, err, "failed to update the boot Job")
+}
@@ -0,0 +1,269 @@
+package joblog
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/loganalyser"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// maxLogLineSize the maximum size of a single log line
+const maxLogLineSize = 1024 * 1024
+
+// logSource a log of a container instance in a pod
+type logSource struct {
+	container string
+	previous  bool
+	filter    *lineFilter
+}
+
+// name returns the pod/container name of the log used to prefix the lines
+func (s *logSource) name(podName string) string {
+	answer := podName + "/" + s.container
+	if s.previous {
+		answer += " (previous)"
+	}
+	return answer
+}
+
+// logLine a line of a log
+type logLine struct {
+	source *logSource
+	text   string
+}
+
+// tailLogs follows the logs of the containers in the pod until they terminate writing each line to the
+// output or emitting it as an event if using JSON output.
+//
+// The init containers are displayed in order then the previous instances of any restarted containers if using
+// --previous then the containers. If more than one log is displayed each line is prefixed with the pod and container name.
+func (o *Options) tailLogs(client kubernetes.Interface, ns string, pod *corev1.Pod, containerName string) error {
+	sequential, concurrent := o.logSources(pod, containerName)
+	prefix := len(sequential)+len(concurrent) > 1
+
+	if o.Summary {
+		o.analyser = loganalyser.NewAnalyser()
+	}
+
+	var errs []error
+	ctx := context.TODO()
+	for _, s := range sequential {
+		err := o.streamLog(ctx, client, ns, pod.Name, s, func(line string) {
+			o.writeLogLine(ns, pod.Name, s, line, prefix)
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(concurrent) == 1 {
+		s := concurrent[0]
+		err := o.streamLog(ctx, client, ns, pod.Name, s, func(line string) {
+			o.writeLogLine(ns, pod.Name, s, line, prefix)
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+		return errors.Join(errs...)
+	}
+
+	// lets follow containers such as sidecars at the same time as they may not terminate until the others do
+	lines := make(chan logLine)
+	results := make(chan error, len(concurrent))
+	var wg sync.WaitGroup
+	for _, s := range concurrent {
+		wg.Add(1)
+		go func(s *logSource) {
+			defer wg.Done()
+			results <- o.streamLog(ctx, client, ns, pod.Name, s, func(line string) {
+				lines <- logLine{source: s, text: line}
+			})
+		}(s)
+	}
+	go func() {
+		wg.Wait()
+		close(lines)
+	}()
+	for l := range lines {
+		o.writeLogLine(ns, pod.Name, l.source, l.text, prefix)
+	}
+	close(results)
+	for err := range results {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// logSources returns the logs to display for the pod. The sequential logs are displayed in order then the
+// concurrent logs are followed at the same time
+func (o *Options) logSources(pod *corev1.Pod, containerName string) ([]*logSource, []*logSource) {
+	var sequential, concurrent []*logSource
+	add := func(c *corev1.Container, init bool) {
+		if !o.AllContainers && c.Name != containerName {
+			return
+		}
+		if o.Previous && restartCount(pod, c.Name, init) > 0 {
+			sequential = append(sequential, o.newLogSource(c.Name, true))
+		}
+		s := o.newLogSource(c.Name, false)
+		if init && !isSidecar(c) {
+			sequential = append(sequential, s)
+			return
+		}
+		concurrent = append(concurrent, s)
+	}
+	for i := range pod.Spec.InitContainers {
+		add(&pod.Spec.InitContainers[i], true)
+	}
+	for i := range pod.Spec.Containers {
+		add(&pod.Spec.Containers[i], false)
+	}
+	return sequential, concurrent
+}
+
+func (o *Options) newLogSource(container string, previous bool) *logSource {
+	s := &logSource{
+		container: container,
+		previous:  previous,
+	}
+	if o.grepRegex != nil {
+		s.filter = newLineFilter(o.grepRegex, o.GrepContext)
+	}
+	return s
+}
+
+// streamLog streams the log of the container instance calling the function with each line
+func (o *Options) streamLog(ctx context.Context, client kubernetes.Interface, ns, podName string, s *logSource, fn func(string)) error {
+	opts := &corev1.PodLogOptions{
+		Container:  s.container,
+		Follow:     !s.previous,
+		Previous:   s.previous,
+		Timestamps: o.requestTimestamps(),
+		SinceTime:  o.sinceTime,
+	}
+	if o.Since > 0 {
+		seconds := int64(o.Since.Seconds())
+		opts.SinceSeconds = &seconds
+	}
+	if o.TailLines >= 0 {
+		tailLines := o.TailLines
+		opts.TailLines = &tailLines
+	}
+	stream, err := client.CoreV1().Pods(ns).GetLogs(podName, opts).Stream(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to stream the log of %s in namespace %s: %w", s.name(podName), ns, err)
+	}
+	defer stream.Close()
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64*1024), maxLogLineSize)
+	for scanner.Scan() {
+		fn(scanner.Text())
+	}
+	err = scanner.Err()
+	if err != nil {
+		return fmt.Errorf("failed to read the log of %s in namespace %s: %w", s.name(podName), ns, err)
+	}
+	return nil
+}
+
+// requestTimestamps returns true if the log lines should be prefixed with timestamps by kubernetes
+func (o *Options) requestTimestamps() bool {
+	return o.JSONOutput() || o.Summary || o.Timestamps
+}
+
+// writeLogLine writes the log line to the output or emits it as an event if using JSON output. If using --summary
+// the line is analysed instead of being written as text. If using --grep only the matching lines and their context
+// are written
+func (o *Options) writeLogLine(ns, podName string, s *logSource, line string, prefix bool) {
+	var t time.Time
+	text := line
+	if o.requestTimestamps() {
+		t, text = splitTimestamp(line)
+	}
+	if o.analyser != nil {
+		o.analyser.AddLine(t, text)
+	}
+	if o.diagnoser != nil {
+		o.diagnoser.AddLine(text)
+	}
+	if o.Summary && !o.JSONOutput() {
+		return
+	}
+	if s.filter == nil {
+		o.writeFilteredLine(ns, podName, s, filteredLine{time: t, text: text}, prefix)
+		return
+	}
+	for _, l := range s.filter.filter(t, text) {
+		o.writeFilteredLine(ns, podName, s, l, prefix)
+	}
+}
+
+func (o *Options) writeFilteredLine(ns, podName string, s *logSource, l filteredLine, prefix bool) {
+	if !o.JSONOutput() {
+		text := l.text
+		if !l.separator {
+			if o.Timestamps && !l.time.IsZero() {
+				text = l.time.Format(time.RFC3339Nano) + " " + text
+			}
+			if prefix {
+				text = info("["+s.name(podName)+"]") + " " + text
+			}
+		}
+		fmt.Fprintln(o.Out, text)
+		return
+	}
+	if l.separator {
+		return
+	}
+	t, text := l.time, l.text
+	o.emit(&Event{
+		Type:      EventLog,
+		Time:      t,
+		Namespace: ns,
+		Pod:       podName,
+		Container: s.container,
+		Previous:  s.previous,
+		Line:      text,
+	})
+}
+
+// splitTimestamp splits the RFC3339 timestamp kubernetes prefixes log lines with from the text of the line
+func splitTimestamp(line string) (time.Time, string) {
+	idx := strings.Index(line, " ")
+	if idx > 0 {
+		t, err := time.Parse(time.RFC3339Nano, line[:idx])
+		if err == nil {
+			return t.UTC(), line[idx+1:]
+		}
+	}
+	return time.Time{}, line
+}
+
+// isSidecar returns true if the init container is a sidecar which keeps running alongside the containers
+func isSidecar(c *corev1.Container) bool {
+	return c.RestartPolicy != nil && *c.RestartPolicy == corev1.ContainerRestartPolicyAlways
+}
+
+// restartCount returns the number of times the container has restarted
+func restartCount(pod *corev1.Pod, name string, init bool) int32 {
+	statuses := pod.Status.ContainerStatuses
+	if init {
+		statuses = pod.Status.InitContainerStatuses
+	}
+	for i := range statuses {
+		if statuses[i].Name == name {
+			return statuses[i].RestartCount
+		}
+	}
+	return 0
+}
@@ -0,0 +1,99 @@
+package joblog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/loganalyser"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/pods"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/table"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// summarise writes the summary of the analysed log of the pod if using --summary
+func (o *Options) summarise(pod *corev1.Pod) {
+	a := o.analyser
+	if a == nil {
+		return
+	}
+	o.analyser = nil
+
+	status := ""
+	if pods.IsPodCompleted(pod) {
+		status = loganalyser.StatusFailed
+		if pods.IsPodSucceeded(pod) {
+			status = loganalyser.StatusSucceeded
+		}
+	}
+	summary := a.Summary(status)
+
+	if o.JSONOutput() {
+		o.emit(&Event{
+			Type:      EventSummary,
+			Namespace: pod.Namespace,
+			Job:       pod.Labels["job-name"],
+			Pod:       pod.Name,
+			Status:    summary.Status,
+			Summary:   summary,
+		})
+		return
+	}
+	o.printSummary(pod.Name, summary)
+}
+
+func (o *Options) printSummary(podName string, summary *loganalyser.Summary) {
+	fmt.Fprintf(o.Out, "\nboot Job pod %s %s\n\n", info(podName), colorStatus(summary.Status))
+
+	t := table.CreateTable(o.Out)
+	t.AddRow("PHASE", "STATUS", "DURATION", "LINES", "RELEASES")
+	for _, p := range summary.Phases {
+		duration := ""
+		if p.Duration > 0 {
+			duration = p.Duration.String()
+		}
+		t.AddRow(p.Name, colorStatus(p.Status), duration, strconv.Itoa(p.Lines), releasesText(p.Releases))
+	}
+	t.Render()
+
+	if summary.Status != loganalyser.StatusFailed {
+		return
+	}
+	fmt.Fprintf(o.Out, "\nthe boot Job failed in phase %s\n", termcolor.ColorError(summary.FailedPhase))
+	if summary.FailedRelease != "" {
+		fmt.Fprintf(o.Out, "the failing release is %s\n", termcolor.ColorError(summary.FailedRelease))
+	}
+	if len(summary.ErrorExcerpt) > 0 {
+		fmt.Fprintf(o.Out, "\n%s\n", termcolor.ColorWarning("error excerpt:"))
+		for _, line := range summary.ErrorExcerpt {
+			fmt.Fprintf(o.Out, "    %s\n", line)
+		}
+	}
+	fmt.Fprintln(o.Out)
+}
+
+func colorStatus(status string) string {
+	switch status {
+	case loganalyser.StatusSucceeded:
+		return info(status)
+	case loganalyser.StatusFailed:
+		return termcolor.ColorError(status)
+	default:
+		return termcolor.ColorWarning(status)
+	}
+}
+
+// releasesText returns the names of the releases highlighting any which failed
+func releasesText(releases []*loganalyser.Release) string {
+	var names []string
+	for _, r := range releases {
+		if r.Status == loganalyser.StatusFailed {
+			names = append(names, termcolor.ColorError(r.Name+" ("+r.Status+")"))
+			continue
+		}
+		names = append(names, r.Name)
+	}
+	return strings.Join(names, ", ")
+}
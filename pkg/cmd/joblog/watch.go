@@ -0,0 +1,320 @@
+package joblog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/jobs"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/pods"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	logger "github.com/jenkins-x/jx-logging/v3/pkg/log"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+// errWatchNotSupported is returned if we cannot watch the resources so need to fall back to polling
+var errWatchNotSupported = errors.New("watching is not supported")
+
+// watcher creates the informer based watches used to wait for boot Jobs and Pods. The informers re-list the
+// resources if a watch expires so that we do not miss any changes. If the API server does not let us watch
+// the resources the watch is cancelled so that we can fall back to polling
+type watcher struct {
+	client kubernetes.Interface
+	ns     string
+	cancel context.CancelFunc
+	lock   sync.Mutex
+	err    error
+}
+
+func newWatcher(client kubernetes.Interface, ns string, cancel context.CancelFunc) *watcher {
+	return &watcher{
+		client: client,
+		ns:     ns,
+		cancel: cancel,
+	}
+}
+
+// jobs returns the ListerWatcher of the Jobs matching the label and field selectors
+func (w *watcher) jobs(labelSelector, fieldSelector string) cache.ListerWatcher {
+	jobInterface := w.client.BatchV1().Jobs(w.ns)
+	lw := &cache.ListWatch{
+		ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			options.LabelSelector = labelSelector
+			options.FieldSelector = fieldSelector
+			answer, err := jobInterface.List(ctx, options)
+			return answer, w.check(err)
+		},
+		WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			options.LabelSelector = labelSelector
+			options.FieldSelector = fieldSelector
+			answer, err := jobInterface.Watch(ctx, options)
+			return answer, w.check(err)
+		},
+	}
+	return cache.ToListWatcherWithWatchListSemantics(lw, w.client)
+}
+
+// pods returns the ListerWatcher of the Pods matching the label selector
+func (w *watcher) pods(labelSelector string) cache.ListerWatcher {
+	podInterface := w.client.CoreV1().Pods(w.ns)
+	lw := &cache.ListWatch{
+		ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			options.LabelSelector = labelSelector
+			answer, err := podInterface.List(ctx, options)
+			return answer, w.check(err)
+		},
+		WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			options.LabelSelector = labelSelector
+			answer, err := podInterface.Watch(ctx, options)
+			return answer, w.check(err)
+		},
+	}
+	return cache.ToListWatcherWithWatchListSemantics(lw, w.client)
+}
+
+// check cancels the watch if we are not allowed to watch the resources as the informer would retry forever
+func (w *watcher) check(err error) error {
+	if err != nil && (apierrors.IsForbidden(err) || apierrors.IsMethodNotSupported(err)) {
+		w.lock.Lock()
+		if w.err == nil {
+			w.err = err
+		}
+		w.lock.Unlock()
+		w.cancel()
+	}
+	return err
+}
+
+// result converts the error of a watch into a timeout error or errWatchNotSupported
+func (w *watcher) result(err error, o *Options) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.err != nil {
+		return fmt.Errorf("%w: %s", errWatchNotSupported, w.err.Error())
+	}
+	if wait.Interrupted(err) {
+		return fmt.Errorf("timed out after waiting for duration %s", o.Duration.String())
+	}
+	return err
+}
+
+// watchForLatestJob watches the boot Jobs until the latest Job for the commit SHA or the latest active Job is found
+func (o *Options) watchForLatestJob(client kubernetes.Interface, ns, selector string) (*batchv1.Job, error) {
+	ctx, cancel := context.WithDeadline(context.Background(), o.timeEnd)
+	defer cancel()
+	w := newWatcher(client, ns, cancel)
+
+	var store cache.Store
+	var answer *batchv1.Job
+	found := func() bool {
+		var jobList []batchv1.Job
+		for _, obj := range store.List() {
+			if job, ok := obj.(*batchv1.Job); ok {
+				jobList = append(jobList, *job)
+			}
+		}
+		job := o.findLatestJob(jobList)
+		if job != nil && (o.CommitSHA != "" || !jobs.IsJobFinished(job)) {
+			answer = job
+			return true
+		}
+		return false
+	}
+	precondition := func(s cache.Store) (bool, error) {
+		store = s
+		return found(), nil
+	}
+	condition := func(event watch.Event) (bool, error) {
+		return found(), nil
+	}
+
+	_, err := watchtools.UntilWithSync(ctx, w.jobs(selector, ""), &batchv1.Job{}, precondition, condition)
+	if err != nil {
+		return nil, w.result(err, o)
+	}
+	return answer, nil
+}
+
+// watchJobCompleteOrPodRunning watches the boot Job and its Pods until either the Job completes or a Pod is running
+func (o *Options) watchJobCompleteOrPodRunning(client kubernetes.Interface, ns, selector, jobName string) (bool, *corev1.Pod, error) {
+	if o.podStatusMap == nil {
+		o.podStatusMap = map[string]string{}
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), o.timeEnd)
+	defer cancel()
+	w := newWatcher(client, ns, cancel)
+
+	type result struct {
+		job *batchv1.Job
+		pod *corev1.Pod
+		err error
+	}
+	results := make(chan result, 3)
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		job, err := o.watchJobComplete(ctx, w, jobName)
+		results <- result{job: job, err: err}
+	}()
+	go func() {
+		defer wg.Done()
+		pod, err := o.watchPodRunning(ctx, w, selector, jobName)
+		results <- result{pod: pod, err: err}
+	}()
+	go func() {
+		defer wg.Done()
+		results <- result{err: o.watchImagePulls(ctx)}
+	}()
+
+	// lets use the first watch to complete then stop the other one
+	r := <-results
+	cancel()
+	wg.Wait()
+
+	if r.err != nil {
+		return false, nil, w.result(r.err, o)
+	}
+	if r.job != nil {
+		o.logJobComplete(r.job)
+		if !jobs.IsJobSucceeded(r.job) {
+			return true, nil, fmt.Errorf("job %s failed", jobName)
+		}
+		return true, nil, nil
+	}
+	return false, r.pod, nil
+}
+
+// watchJobComplete watches the Job until it completes
+func (o *Options) watchJobComplete(ctx context.Context, w *watcher, jobName string) (*batchv1.Job, error) {
+	var answer *batchv1.Job
+	condition := func(event watch.Event) (bool, error) {
+		job, ok := event.Object.(*batchv1.Job)
+		if !ok || job.Name != jobName {
+			return false, nil
+		}
+		if event.Type == watch.Deleted {
+			return false, fmt.Errorf("job %s was deleted", jobName)
+		}
+		if jobs.IsJobFinished(job) {
+			answer = job
+			return true, nil
+		}
+		logger.Logger().Debugf("boot Job %s is not completed yet", info(job.Name))
+		return false, nil
+	}
+	_, err := watchtools.UntilWithSync(ctx, w.jobs("", fields.OneTermEqualSelector("metadata.name", jobName).String()), &batchv1.Job{}, nil, condition)
+	return answer, err
+}
+
+// watchPodRunning watches the Pods matching the selector until one is running or ready. Records any pods of the Job
+// which cannot pull their images so that watchImagePulls can give up once the image pull timeout is reached
+func (o *Options) watchPodRunning(ctx context.Context, w *watcher, selector, jobName string) (*corev1.Pod, error) {
+	podSelector, err := labels.Parse(selector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse selector %s: %w", selector, err)
+	}
+	var answer *corev1.Pod
+	condition := func(event watch.Event) (bool, error) {
+		pod, ok := event.Object.(*corev1.Pod)
+		if !ok || !podSelector.Matches(labels.Set(pod.Labels)) {
+			return false, nil
+		}
+		if event.Type == watch.Deleted {
+			o.imagePulls.remove(pod.Name)
+			return false, nil
+		}
+		o.logPodStatus(pod)
+		if pod.Labels["job-name"] == jobName {
+			o.imagePulls.update(pod)
+		}
+		if pod.Status.Phase == corev1.PodRunning || pods.IsPodReady(pod) {
+			answer = pod
+			return true, nil
+		}
+		return false, nil
+	}
+	_, err = watchtools.UntilWithSync(ctx, w.pods(selector), &corev1.Pod{}, nil, condition)
+	return answer, err
+}
+
+// watchImagePulls checks the image pull failures recorded by watchPodRunning every poll period and fails if a
+// container has been failing to pull its image for longer than the image pull timeout so that we can diagnose the
+// failure rather than waiting until we time out
+func (o *Options) watchImagePulls(ctx context.Context) error {
+	ticker := time.NewTicker(o.PollPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			err := o.imagePulls.check(o.ImagePullTimeout)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// logPodStatus logs the status of an active pod if it has changed
+func (o *Options) logPodStatus(pod *corev1.Pod) {
+	status := pods.PodStatus(pod)
+	if o.podStatusMap[pod.Name] == status || pods.IsPodCompleted(pod) || pod.DeletionTimestamp != nil {
+		return
+	}
+	logger.Logger().Infof("pod %s has status %s", termcolor.ColorInfo(pod.Name), termcolor.ColorInfo(status))
+	o.podStatusMap[pod.Name] = status
+	o.emitPodStatus(pod, status)
+}
+
+// logPodComplete logs the status of a pod after its log has been tailed
+func (o *Options) logPodComplete(pod *corev1.Pod) {
+	podName := pod.Name
+	switch {
+	case pods.IsPodCompleted(pod):
+		if pods.IsPodSucceeded(pod) {
+			logger.Logger().Infof("boot Job pod %s has %s", info(podName), info("Succeeded"))
+		} else {
+			logger.Logger().Infof("boot Job pod %s has %s", info(podName), termcolor.ColorError(string(pod.Status.Phase)))
+		}
+		o.emitPodStatus(pod, string(pod.Status.Phase))
+	case pod.DeletionTimestamp != nil:
+		logger.Logger().Infof("boot Job pod %s is %s", info(podName), termcolor.ColorWarning("Terminating"))
+		o.emitPodStatus(pod, "Terminating")
+	}
+}
+
+func (o *Options) emitPodStatus(pod *corev1.Pod, status string) {
+	o.emit(&Event{
+		Type:      EventPodStatus,
+		Namespace: pod.Namespace,
+		Job:       pod.Labels["job-name"],
+		Pod:       pod.Name,
+		Status:    status,
+	})
+}
+
+// logJobComplete logs the result of a finished Job
+func (o *Options) logJobComplete(job *batchv1.Job) {
+	if jobs.IsJobSucceeded(job) {
+		logger.Logger().Infof("boot Job %s has %s", info(job.Name), info("Succeeded"))
+	} else {
+		logger.Logger().Infof("boot Job %s has %s", info(job.Name), termcolor.ColorError("Failed"))
+	}
+	o.emitJobFinished(job)
+}
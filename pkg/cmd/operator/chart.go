@@ -0,0 +1,118 @@
+package operator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+)
+
+const ociPrefix = "oci://"
+
+// isOCIChart returns true if the chart is a reference to a chart in an OCI registry
+func isOCIChart(chart string) bool {
+	return strings.HasPrefix(chart, ociPrefix)
+}
+
+// isLocalChart returns true if the chart is a local chart archive or chart directory
+func isLocalChart(chart string) bool {
+	if chart == "" {
+		return false
+	}
+	if strings.HasSuffix(chart, ".tgz") || strings.HasSuffix(chart, ".tar.gz") {
+		return true
+	}
+	exists, err := files.DirExists(chart)
+	if err == nil && exists {
+		return true
+	}
+	return chart[0] == '.' || chart[0] == '/' || chart[0] == '\\'
+}
+
+// usesChartRepository returns true if the chart is in a helm chart repository so that we need to add
+// the repository before installing the chart
+func (o *Options) usesChartRepository() bool {
+	return !isOCIChart(o.ChartName) && !isLocalChart(o.ChartName)
+}
+
+// verifyChart verifies the checksum of a local chart archive if a checksum file is specified
+func (o *Options) verifyChart() error {
+	if o.ChartChecksumFile == "" {
+		return nil
+	}
+	chart := o.ChartName
+	if isOCIChart(chart) || !isLocalChart(chart) {
+		return options.InvalidOptionf("chart-checksum-file", o.ChartChecksumFile, "checksums can only be verified for a local chart archive. Use 'helm pull' to download the chart %s first", chart)
+	}
+	exists, err := files.FileExists(chart)
+	if err != nil {
+		return fmt.Errorf("failed to check if file %s exists: %w", chart, err)
+	}
+	if !exists {
+		return options.InvalidOptionf("chart-checksum-file", o.ChartChecksumFile, "checksums can only be verified for a local chart archive and %s is not a file", chart)
+	}
+
+	data, err := os.ReadFile(o.ChartChecksumFile)
+	if err != nil {
+		return fmt.Errorf("failed to load checksum file %s: %w", o.ChartChecksumFile, err)
+	}
+	expected, err := findChecksum(string(data), filepath.Base(chart))
+	if err != nil {
+		return fmt.Errorf("invalid checksum file %s: %w", o.ChartChecksumFile, err)
+	}
+	actual, err := fileChecksum(chart)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(expected, actual) {
+		return fmt.Errorf("the sha256 checksum %s of chart %s does not match the checksum %s in file %s", actual, chart, expected, o.ChartChecksumFile)
+	}
+	log.Logger().Infof("verified the sha256 checksum of chart %s", termcolor.ColorInfo(chart))
+	return nil
+}
+
+// findChecksum finds the checksum of the file in the output of 'sha256sum' or a file only containing the checksum
+func findChecksum(text, fileName string) (string, error) {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 1 && len(lines) == 1 {
+			return fields[0], nil
+		}
+		// sha256sum prefixes the file name with '*' in binary mode
+		if len(fields) == 2 && filepath.Base(strings.TrimPrefix(fields[1], "*")) == fileName {
+			return fields[0], nil
+		}
+	}
+	return "", fmt.Errorf("no sha256 checksum found for %s", fileName)
+}
+
+// fileChecksum returns the hex encoded sha256 checksum of the file
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file %s: %w", path, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,246 @@
+package operator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/helmsdk"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/input/survey"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pmezard/go-difflib/difflib"
+	"sigs.k8s.io/yaml"
+)
+
+// secretValueKeys the lower case value keys which contain secrets that should not be displayed
+var secretValueKeys = []string{"password", "token", "privatekey"}
+
+// showDiff displays the changes the install or upgrade will make to the deployed git operator and asks the user
+// to confirm them unless in batch mode. Returns false if the user does not want to continue
+func (o *Options) showDiff() (bool, error) {
+	deployedManifest, deployedValues, err := o.getDeployedRelease()
+	if err != nil {
+		return false, err
+	}
+	manifest, err := o.getRenderedManifest(deployedManifest != "")
+	if err != nil {
+		return false, err
+	}
+	values, err := helmsdk.MergeValues(o.ValuesFiles, o.getSetValues(o.GitURL))
+	if err != nil {
+		return false, err
+	}
+
+	valuesDiff, err := diffValues(deployedValues, values)
+	if err != nil {
+		return false, err
+	}
+	manifestDiff, err := unifiedDiff(maskManifest(deployedManifest), maskManifest(manifest), "deployed/manifest.yaml", "new/manifest.yaml")
+	if err != nil {
+		return false, err
+	}
+	if valuesDiff == "" && manifestDiff == "" {
+		log.Logger().Infof("there are no changes to release %s in namespace %s", termcolor.ColorInfo(o.ReleaseName), termcolor.ColorInfo(o.Namespace))
+		return true, nil
+	}
+
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+	fmt.Fprint(o.Out, colorDiff(valuesDiff+manifestDiff))
+
+	if o.BatchMode || o.DryRun {
+		return true, nil
+	}
+	if o.Input == nil {
+		o.Input = survey.NewInput()
+	}
+	return o.Input.Confirm(fmt.Sprintf("Do you want to apply these changes to release %s in namespace %s?", o.ReleaseName, o.Namespace), false, "the changes are made by running helm upgrade on the git operator chart")
+}
+
+// getDeployedRelease returns the manifest and user supplied values of the deployed release or empty values if it is not installed
+func (o *Options) getDeployedRelease() (string, map[string]interface{}, error) {
+	if o.HelmSDK {
+		rel, err := o.HelmClient.GetRelease(o.ReleaseName, o.Namespace)
+		if err != nil || rel == nil {
+			return "", nil, err
+		}
+		return rel.Manifest, rel.Values, nil
+	}
+
+	manifest, err := o.CommandRunner(&cmdrunner.Command{
+		Name: o.HelmBin,
+		Args: append([]string{"get", "manifest", o.ReleaseName, "--namespace", o.Namespace}, o.KubeConfig.HelmArgs()...),
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			log.Logger().Infof("release %s is not installed in namespace %s", termcolor.ColorInfo(o.ReleaseName), termcolor.ColorInfo(o.Namespace))
+			return "", nil, nil
+		}
+		return "", nil, fmt.Errorf("failed to get the manifest of release %s in namespace %s: %w", o.ReleaseName, o.Namespace, err)
+	}
+	text, err := o.CommandRunner(&cmdrunner.Command{
+		Name: o.HelmBin,
+		Args: append([]string{"get", "values", o.ReleaseName, "--namespace", o.Namespace, "--output", "yaml"}, o.KubeConfig.HelmArgs()...),
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get the values of release %s in namespace %s: %w", o.ReleaseName, o.Namespace, err)
+	}
+	values := map[string]interface{}{}
+	err = yaml.Unmarshal([]byte(text), &values)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse the values of release %s in namespace %s: %w", o.ReleaseName, o.Namespace, err)
+	}
+	return manifest, values, nil
+}
+
+// getRenderedManifest renders the manifest of the chart that is going to be installed. The chart is rendered against
+// the cluster so that it uses the same API versions and capabilities as the deployed release
+func (o *Options) getRenderedManifest(isUpgrade bool) (string, error) {
+	if o.HelmSDK {
+		opts := o.getReleaseOptions(o.GitURL)
+		opts.Validate = true
+		opts.IsUpgrade = isUpgrade
+		manifest, err := o.HelmClient.Template(opts)
+		if err != nil {
+			return "", fmt.Errorf("failed to render the git operator chart: %w", err)
+		}
+		return manifest, nil
+	}
+	c := o.getTemplateCommandLine(o.HelmBin, o.GitURL)
+	c.Args = append(c.Args, "--validate")
+	if isUpgrade {
+		c.Args = append(c.Args, "--is-upgrade")
+	}
+	c.Args = append(c.Args, o.KubeConfig.HelmArgs()...)
+	manifest, err := o.CommandRunner(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to render the git operator chart: %w", err)
+	}
+	return manifest, nil
+}
+
+// diffValues returns the unified diff of the values with any secrets masked
+func diffValues(deployed, values map[string]interface{}) (string, error) {
+	var texts []string
+	for _, v := range []map[string]interface{}{deployed, values} {
+		text := ""
+		if len(v) > 0 {
+			data, err := yaml.Marshal(maskValues(v))
+			if err != nil {
+				return "", fmt.Errorf("failed to marshal values: %w", err)
+			}
+			text = string(data)
+		}
+		texts = append(texts, text)
+	}
+	return unifiedDiff(texts[0], texts[1], "deployed/values.yaml", "new/values.yaml")
+}
+
+func unifiedDiff(from, to, fromFile, toFile string) (string, error) {
+	if from == to {
+		return "", nil
+	}
+	text, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(from),
+		B:        difflib.SplitLines(to),
+		FromFile: fromFile,
+		ToFile:   toFile,
+		Context:  3,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to diff %s: %w", toFile, err)
+	}
+	return text, nil
+}
+
+// colorDiff colourises the lines of a unified diff
+func colorDiff(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "+++") || strings.HasPrefix(line, "---"):
+			lines[i] = termcolor.ColorBold(line)
+		case strings.HasPrefix(line, "@@"):
+			lines[i] = termcolor.ColorStatus(line)
+		case strings.HasPrefix(line, "+"):
+			lines[i] = termcolor.ColorInfo(line)
+		case strings.HasPrefix(line, "-"):
+			lines[i] = termcolor.ColorError(line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// maskValues returns a copy of the values with any secret values masked
+func maskValues(values map[string]interface{}) map[string]interface{} {
+	answer := map[string]interface{}{}
+	for k, v := range values {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			answer[k] = maskValues(t)
+		case string:
+			if isSecretValueKey(k) {
+				answer[k] = maskValue(t)
+			} else {
+				answer[k] = t
+			}
+		default:
+			answer[k] = v
+		}
+	}
+	return answer
+}
+
+func isSecretValueKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range secretValueKeys {
+		if strings.Contains(key, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskManifest masks the data of any Secrets in the manifest
+func maskManifest(manifest string) string {
+	docs := strings.Split(manifest, "\n---")
+	for i, doc := range docs {
+		if !strings.Contains(doc, "\nkind: Secret") {
+			continue
+		}
+		lines := strings.Split(doc, "\n")
+		inData := false
+		for j, line := range lines {
+			if !strings.HasPrefix(line, " ") {
+				inData = line == "data:" || line == "stringData:"
+				continue
+			}
+			if !inData {
+				continue
+			}
+			idx := strings.Index(line, ":")
+			if idx > 0 {
+				value := strings.TrimSpace(line[idx+1:])
+				if value != "" {
+					lines[j] = line[:idx+1] + " " + maskValue(value)
+				}
+			}
+		}
+		docs[i] = strings.Join(lines, "\n")
+	}
+	return strings.Join(docs, "\n---")
+}
+
+// maskValue hides the secret value but includes a short hash so that changes are still visible in the diff
+func maskValue(value string) string {
+	if value == "" {
+		return value
+	}
+	h := sha256.Sum256([]byte(value))
+	return "****" + hex.EncodeToString(h[:4])
+}
@@ -0,0 +1,150 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/netconfig"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/jxenv"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// CAConfigMapName the name of the ConfigMap containing the CA bundle of the git server
+	CAConfigMapName = "jx-git-operator-ca"
+
+	caVolumeName = "git-ca"
+	caMountPath  = "/etc/jx-git-operator/ca"
+)
+
+// getNetworkSetValues returns the helm set values which mount the CA bundle and configure the proxies in the git operator
+func (o *Options) getNetworkSetValues() []string {
+	n := &o.Network
+	var answer []string
+	for _, e := range []struct {
+		name  string
+		value string
+	}{
+		{"HTTP_PROXY", n.HTTPProxy},
+		{"HTTPS_PROXY", n.HTTPSProxy},
+		{"NO_PROXY", n.NoProxy},
+	} {
+		if e.value != "" {
+			// helm splits --set values on commas
+			answer = append(answer, fmt.Sprintf("env.%s=%s", e.name, strings.ReplaceAll(e.value, ",", "\\,")))
+		}
+	}
+	if n.CAFile != "" {
+		answer = append(answer,
+			"extraVolumes[0].name="+caVolumeName,
+			"extraVolumes[0].configMap.name="+CAConfigMapName,
+			"extraVolumeMounts[0].name="+caVolumeName,
+			"extraVolumeMounts[0].mountPath="+caMountPath,
+			"extraVolumeMounts[0].readOnly=true",
+		)
+	}
+	return answer
+}
+
+// getNetworkGitCommands returns the git commands to configure git inside the git operator to trust the CA bundle
+func (o *Options) getNetworkGitCommands() []string {
+	if o.Network.CAFile == "" {
+		return nil
+	}
+	return []string{fmt.Sprintf("git config --global http.sslCAInfo %s/%s", caMountPath, netconfig.CAFileKey)}
+}
+
+// newCAConfigMap creates the ConfigMap containing the CA bundle
+func (o *Options) newCAConfigMap() (*corev1.ConfigMap, error) {
+	data, err := o.Network.LoadCA()
+	if err != nil {
+		return nil, err
+	}
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      CAConfigMapName,
+			Namespace: o.Namespace,
+		},
+		Data: map[string]string{
+			netconfig.CAFileKey: string(data),
+		},
+	}, nil
+}
+
+// ensureCAConfigMap creates or updates the ConfigMap containing the CA bundle which the git operator mounts
+func (o *Options) ensureCAConfigMap() error {
+	if o.Network.CAFile == "" {
+		return nil
+	}
+	cm, err := o.newCAConfigMap()
+	if err != nil {
+		return err
+	}
+	ns := o.Namespace
+	name := cm.Name
+	if o.DryRun {
+		log.Logger().Infof("would create or update ConfigMap %s in namespace %s with the CA bundle %s", termcolor.ColorInfo(name), termcolor.ColorInfo(ns), termcolor.ColorInfo(o.Network.CAFile))
+		return nil
+	}
+
+	o.KubeClient, err = o.KubeConfig.LazyCreateKubeClient(o.KubeClient)
+	if err != nil {
+		return fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+	if !o.SkipNamespaceCreation {
+		err = jxenv.EnsureNamespaceCreated(o.KubeClient, ns, nil, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create namespace %s: %w", ns, err)
+		}
+	}
+
+	ctx := context.TODO()
+	cmInterface := o.KubeClient.CoreV1().ConfigMaps(ns)
+	existing, err := cmInterface.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to find ConfigMap %s in namespace %s: %w", name, ns, err)
+		}
+		_, err = cmInterface.Create(ctx, cm, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to create ConfigMap %s in namespace %s: %w", name, ns, err)
+		}
+	} else {
+		existing.Data = cm.Data
+		_, err = cmInterface.Update(ctx, existing, metav1.UpdateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to update ConfigMap %s in namespace %s: %w", name, ns, err)
+		}
+	}
+	log.Logger().Infof("created or updated ConfigMap %s in namespace %s with the CA bundle", termcolor.ColorInfo(name), termcolor.ColorInfo(ns))
+	return nil
+}
+
+// saveCAConfigMap saves the ConfigMap containing the CA bundle into the directory
+func (o *Options) saveCAConfigMap(dir string) error {
+	if o.Network.CAFile == "" {
+		return nil
+	}
+	cm, err := o.newCAConfigMap()
+	if err != nil {
+		return err
+	}
+	fileName := filepath.Join(dir, CAConfigMapName+"-configmap.yaml")
+	err = yamls.SaveFile(cm, fileName)
+	if err != nil {
+		return fmt.Errorf("failed to save ConfigMap file %s: %w", fileName, err)
+	}
+	return nil
+}
@@ -2,60 +2,109 @@ package operator
 
 import (
 	"fmt"
+	"io"
+	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/jenkins-x-plugins/jx-admin/pkg/cmd/joblog"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/cmd/operator/repo"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/cmd/operator/rollback"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/cmd/operator/rotate"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/cmd/operator/status"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/cmd/operator/uninstall"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/cmd/preflight"
 	"github.com/jenkins-x-plugins/jx-admin/pkg/common"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/gitcreds"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/helmsdk"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/kubeconfig"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/netconfig"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/operatorsecrets"
 	"github.com/jenkins-x-plugins/jx-admin/pkg/plugins/helmplugin"
+	"github.com/jenkins-x/go-scm/scm"
 	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/cli"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/gitconfig"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/giturl"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/helmer"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/input"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/input/survey"
-	"github.com/jenkins-x/jx-helpers/v3/pkg/kube"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
-	"github.com/jenkins-x/jx-kube-client/v3/pkg/kubeclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/versionstream"
 	"github.com/jenkins-x/jx-logging/v3/pkg/log"
 
 	"github.com/spf13/cobra"
-	"k8s.io/client-go/tools/clientcmd"
+	"golang.org/x/term"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
 )
 
 // Options contains the command line arguments for this command
 type Options struct {
-	Dir                   string
-	GitURL                string
-	GitUserName           string
-	GitToken              string
-	Namespace             string
-	ReleaseName           string
-	ChartName             string
-	ChartVersion          string
-	HelmBin               string
-	GitSetupCommands      []string
-	HelmSetArgs           []string
-	DryRun                bool
-	NoSwitchNamespace     bool
-	NoLog                 bool
-	BatchMode             bool
-	JobLogOptions         joblog.Options
-	CommandRunner         cmdrunner.CommandRunner
-	Helmer                helmer.Helmer
-	SkipNamespaceCreation bool
+	Dir                     string
+	GitURL                  string
+	GitUserName             string
+	GitToken                string
+	Namespace               string
+	ReleaseName             string
+	ChartName               string
+	ChartVersion            string
+	ChartChecksumFile       string
+	ChartKeyring            string
+	VersionStreamURL        string
+	VersionStreamRef        string
+	HelmBin                 string
+	GitSetupCommands        []string
+	HelmSetArgs             []string
+	ValuesFiles             []string
+	OutputDir               string
+	GitKind                 string
+	GitHubAppID             string
+	GitHubAppInstallationID string
+	GitHubAppPrivateKeyFile string
+	Network                 netconfig.Config
+	KubeConfig              kubeconfig.Options
+	SecretMode              string
+	SecretName              string
+	ChartVerify             bool
+	DryRun                  bool
+	Diff                    bool
+	NoVerify                bool
+	SkipPreflight           bool
+	HelmSDK                 bool
+	NoSwitchNamespace       bool
+	CreateContext           bool
+	NoLog                   bool
+	BatchMode               bool
+	JobLogOptions           joblog.Options
+	Out                     io.Writer
+	Input                   input.Interface
+	CommandRunner           cmdrunner.CommandRunner
+	Helmer                  helmer.Helmer
+	HelmClient              helmsdk.Interface
+	Gitter                  gitclient.Interface
+	ScmClient               *scm.Client
+	HTTPClient              *http.Client
+	KubeClient              kubernetes.Interface
+	DynamicClient           dynamic.Interface
+	SkipNamespaceCreation   bool
 }
 
 var (
 	cmdLong = templates.LongDesc(`
 		Installs the git operator in a cluster
 
+		If the git server uses an internal CA or is behind a proxy use --ca-file, --http-proxy, --https-proxy and --no-proxy. The CA bundle is stored in a ConfigMap and configured in git inside the git operator along with the proxies.
 `)
 
 	cmdExample = templates.Examples(`
@@ -67,6 +116,40 @@ var (
 ` + bashExample("operator --url https://github.com/myorg/environment-mycluster-dev.git --username myuser --token myuser") + `
 * display what helm command will install the git operator
 ` + bashExample("operator --dry-run") + `
+* installs the git operator creating the git credentials Secret via the kubernetes API rather than passing the token to helm
+` + bashExample("operator --secret-mode kube") + `
+* installs the git operator using an existing Secret or ExternalSecret for the git credentials
+` + bashExample("operator --secret-mode existing --secret-name my-git-secret") + `
+* displays the changes an upgrade of the git operator will make and asks for confirmation before applying them
+` + bashExample("operator --diff") + `
+* installs the git operator using a helm values file
+` + bashExample("operator -f my-values.yaml") + `
+* renders the git operator manifests and the git credentials Secret into a directory rather than installing them
+` + bashExample("operator --output-dir ./bootstrap/jx-git-operator --secret-mode kube") + `
+* installs the git operator from a local chart archive in an air gapped environment verifying its checksum
+` + bashExample("operator --chart ./jx-git-operator-0.1.2.tgz --chart-checksum-file ./jx-git-operator-0.1.2.tgz.sha256") + `
+* installs the git operator from a chart in an internal OCI registry
+` + bashExample("operator --chart oci://registry.example.com/charts/jx-git-operator --chart-version 0.1.2") + `
+* installs the git operator for a git server using an internal CA behind a proxy
+` + bashExample("operator --ca-file my-ca.crt --https-proxy http://proxy.example.com:3128 --no-proxy .svc,.cluster.local") + `
+* installs the git operator into the cluster of the given kube context creating a dedicated 'jx-<cluster>' context for the operator namespace
+` + bashExample("operator --context my-cluster-admin --create-context") + `
+* installs the git operator in process using the helm Go SDK without a helm binary
+` + bashExample("operator --helm-sdk") + `
+* installs the git operator using the chart version from the given version stream
+` + bashExample("operator --version-stream-url https://github.com/jenkins-x/jx3-versions.git --version-stream-ref v1.2.3") + `
+* displays the status of the git operator and the git repositories it boots
+` + bashExample("operator status") + `
+* registers an additional tenant git repository for the git operator to boot
+` + bashExample("operator repo add tenant-a --url https://github.com/myorg/tenant-a-config.git --repo-namespace tenant-a") + `
+* lists the git repositories the git operator boots
+` + bashExample("operator repo list") + `
+* uninstalls the git operator
+` + bashExample("operator uninstall") + `
+* rolls back the git operator to a previous revision of its helm release
+` + bashExample("operator rollback") + `
+* rotates the git token used by the git operator without reinstalling it
+` + bashExample("operator rotate-credentials --username mybotuser --token mynewtoken") + `
 `)
 )
 
@@ -76,7 +159,8 @@ func bashExample(cli string) string {
 }
 
 const (
-	defaultChartName = "jxgh/jx-git-operator"
+	jxghRepoName     = "jxgh"
+	defaultChartName = jxghRepoName + "/jx-git-operator"
 )
 
 // NewCmdRun creates the new command
@@ -105,8 +189,13 @@ func NewCmdOperator() (*cobra.Command, *Options) {
 	command.Flags().StringVarP(&options.Namespace, "namespace", "n", common.DefaultOperatorNamespace, "the namespace to install the git operator")
 	command.Flags().StringArrayVarP(&options.GitSetupCommands, "setup", "", nil, "a git configuration command to configure git inside the git operator pod to deal with things like insecure docker registries etc. e.g. supply 'git config --global http.sslverify false' to disable TLS verification")
 	command.Flags().StringArrayVarP(&options.HelmSetArgs, "set", "", nil, "one or more helm set arguments to pass through the git operator chart. Equivalent to running 'helm install --set some.name=value'")
+	command.Flags().StringArrayVarP(&options.ValuesFiles, "values", "f", nil, "one or more helm values files to customise the git operator chart. Equivalent to running 'helm install --values myvalues.yaml'")
+	command.Flags().StringVarP(&options.GitKind, "git-kind", "", "", "the kind of git server used to verify the git credentials. If not specified it is detected for the common SaaS git providers or from the requirements")
+	command.Flags().StringVarP(&options.OutputDir, "output-dir", "", "", "if specified the git operator manifests are rendered into this directory rather than installed into the cluster. If --secret-mode is kube the git credentials Secret is also written to this directory. If a git token is used --secret-mode must be kube or existing so that the token is not rendered into the chart manifests")
+	command.Flags().BoolVarP(&options.Diff, "diff", "", false, "displays a diff of the deployed git operator manifest and values with the new chart version and values then asks for confirmation before applying them unless in batch mode")
 	command.Flags().BoolVarP(&options.NoLog, "no-log", "", false, "to disable viewing the logs of the boot Job pods")
 	command.Flags().BoolVarP(&options.NoSwitchNamespace, "no-switch-namespace", "", false, "to disable switching to the installation namespace after installing the operator")
+	command.Flags().BoolVarP(&options.CreateContext, "create-context", "", false, "creates a dedicated kube context called 'jx-<cluster>' using the installation namespace and switches to it rather than changing the namespace of the existing context")
 
 	command.Flags().DurationVarP(&options.JobLogOptions.Duration, "max-log-duration", "", time.Minute*30, "how long to wait for a boot Job pod to be ready to view its log")
 
@@ -118,15 +207,35 @@ func NewCmdOperator() (*cobra.Command, *Options) {
 
 	options.AddFlags(command)
 
+	command.AddCommand(cobras.SplitCommand(uninstall.NewCmdUninstall()))
+	command.AddCommand(cobras.SplitCommand(rotate.NewCmdRotateCredentials()))
+	command.AddCommand(cobras.SplitCommand(rollback.NewCmdRollback()))
+	command.AddCommand(cobras.SplitCommand(status.NewCmdStatus()))
+	command.AddCommand(repo.NewCmdRepo())
 	return command, options
 }
 
 func (o *Options) AddFlags(command *cobra.Command) {
-	command.Flags().StringVarP(&o.ReleaseName, "name", "", "jxgo", "the helm release name t ouse")
-	command.Flags().StringVarP(&o.ChartName, "chart", "", defaultChartName, "the chart name to use to install the git operator")
-	command.Flags().StringVarP(&o.ChartVersion, "chart-version", "", "", "override the helm chart version used for the git operator")
+	command.Flags().StringVarP(&o.ReleaseName, "name", "", common.DefaultOperatorReleaseName, "the helm release name t ouse")
+	command.Flags().StringVarP(&o.ChartName, "chart", "", defaultChartName, "the chart to use to install the git operator. Can be a chart in a helm repository, a local chart archive (.tgz) or directory or an 'oci://' reference to a chart in an OCI registry. The helm repository is only added for charts in a helm repository")
+	command.Flags().StringVarP(&o.ChartChecksumFile, "chart-checksum-file", "", "", "the file containing the sha256 checksum of the local chart archive in the format of 'sha256sum'. If specified the checksum is verified before installing the chart")
+	command.Flags().BoolVarP(&o.ChartVerify, "verify", "", false, "verifies the provenance file of the chart before installing it. Equivalent to running 'helm install --verify'")
+	command.Flags().StringVarP(&o.ChartKeyring, "keyring", "", "", "the keyring containing the public keys used to verify the provenance file of the chart if --verify is enabled. If not specified the helm default keyring is used")
+	command.Flags().StringVarP(&o.ChartVersion, "chart-version", "", "", "override the helm chart version used for the git operator. If not specified the version is resolved from the version stream")
+	command.Flags().StringVarP(&o.VersionStreamURL, "version-stream-url", "", "", "the git URL or local directory of the version stream used to resolve the chart version. If not specified the versionStream folder inside the --dir directory is used")
+	command.Flags().StringVarP(&o.VersionStreamRef, "version-stream-ref", "", "", "the git ref (branch, tag or SHA) of the version stream to use if --version-stream-url is a git URL")
 	command.Flags().BoolVarP(&o.DryRun, "dry-run", "", false, "if enabled just display the helm command that will run but don't actually do anything")
 	command.Flags().BoolVarP(&o.SkipNamespaceCreation, "skip-namespace-creation", "", false, "if enabled skip namespace creation")
+	command.Flags().StringVarP(&o.SecretMode, "secret-mode", "", SecretModeHelm, fmt.Sprintf("how the git credentials are passed to the git operator. Possible values: %s. Use '%s' to create the Secret via the kubernetes API or '%s' to use an existing Secret or ExternalSecret so that no credentials are passed to helm", strings.Join(SecretModes, ", "), SecretModeKube, SecretModeExisting))
+	command.Flags().StringVarP(&o.SecretName, "secret-name", "", operatorsecrets.DefaultSecretName, "the name of the git operator Secret to create or use if --secret-mode is not helm")
+	command.Flags().BoolVarP(&o.NoVerify, "no-verify", "", false, "disables verifying the git credentials can read the git repository before installing the git operator")
+	command.Flags().StringVarP(&o.GitHubAppID, "github-app-id", "", "", "the ID of the GitHub App used to authenticate the git operator. Not supported yet as the git operator cannot mint its own installation tokens")
+	command.Flags().StringVarP(&o.GitHubAppInstallationID, "github-app-installation-id", "", "", "the installation ID of the GitHub App in the organisation of the git repository. Not supported yet")
+	command.Flags().StringVarP(&o.GitHubAppPrivateKeyFile, "github-app-private-key", "", "", "the file containing the PEM encoded private key of the GitHub App. Not supported yet")
+	command.Flags().BoolVarP(&o.SkipPreflight, "skip-preflight", "", false, "disables the preflight checks of the cluster before installing the git operator")
+	command.Flags().BoolVarP(&o.HelmSDK, "helm-sdk", "", false, "if enabled install the chart in process using the helm Go SDK rather than running a downloaded helm binary")
+	o.Network.AddFlags(command)
+	o.KubeConfig.AddFlags(command)
 }
 
 // Run installs the git operator chart
@@ -140,73 +249,147 @@ func (o *Options) Run() error {
 	if o.GitToken == "" {
 		o.GitToken = os.Getenv("GIT_TOKEN")
 	}
-	var err error
-	if o.GitURL == "" {
-		o.GitURL, err = findGitURLFromDir(o.Dir)
+	err := o.validateSecretMode()
+	if err != nil {
+		return err
+	}
+	err = gitcreds.RejectGitHubApp(o.GitHubAppID, o.GitHubAppInstallationID, o.GitHubAppPrivateKeyFile)
+	if err != nil {
+		return err
+	}
+	if o.HTTPClient == nil {
+		o.HTTPClient, err = o.Network.HTTPClient()
 		if err != nil {
-			return fmt.Errorf("failed to detect the git URL from the directory %s: %w", o.Dir, err)
+			return fmt.Errorf("failed to configure the CA and proxies: %w", err)
 		}
 	}
-	if o.GitURL != "" {
-		o.GitURL, err = o.ensureValidGitURL(o.GitURL)
+	o.CommandRunner, err = o.Network.CommandRunner(o.CommandRunner)
+	if err != nil {
+		return fmt.Errorf("failed to configure the CA and proxies for git: %w", err)
+	}
+	if o.OutputDir == "" {
+		confirmed, err := o.confirmCluster()
 		if err != nil {
-			return fmt.Errorf("failed to ensure the git URL is valid: %w", err)
+			return err
+		}
+		if !confirmed {
+			log.Logger().Infof("not installing the git operator")
+			return nil
 		}
 	}
-	if o.HelmBin == "" {
-		o.HelmBin, err = helmplugin.GetHelm3Binary()
+	if !o.SkipPreflight && !o.DryRun && o.OutputDir == "" {
+		err = o.runPreflight()
 		if err != nil {
 			return err
 		}
 	}
-	if o.HelmBin == "" {
-		return fmt.Errorf("no helm binary found")
+	if o.SecretMode != SecretModeExisting {
+		if o.GitURL == "" {
+			o.GitURL, err = findGitURLFromDir(o.Dir)
+			if err != nil {
+				return fmt.Errorf("failed to detect the git URL from the directory %s: %w", o.Dir, err)
+			}
+		}
+		err = gitcreds.RejectSSHURL("url", o.GitURL)
+		if err != nil {
+			return err
+		}
+		if o.GitURL != "" {
+			o.GitURL, err = o.ensureValidGitURL(o.GitURL)
+			if err != nil {
+				return fmt.Errorf("failed to ensure the git URL is valid: %w", err)
+			}
+			err = o.verifyGitCredentials()
+			if err != nil {
+				return err
+			}
+		}
 	}
-
-	// lets add helm repository for jx-labs
-	if o.Helmer == nil {
-		o.Helmer = helmplugin.NewHelmer(o.HelmBin, o.Dir)
+	if o.HelmSDK {
+		if o.HelmClient == nil {
+			client := helmsdk.NewClient()
+			if o.KubeConfig.File != "" {
+				client.Settings.KubeConfig = o.KubeConfig.File
+			}
+			if o.KubeConfig.Context != "" {
+				client.Settings.KubeContext = o.KubeConfig.Context
+			}
+			o.HelmClient = client
+		}
+		if o.HelmBin == "" {
+			// only used to display the equivalent helm command
+			o.HelmBin = "helm"
+		}
+	} else if o.usesChartRepository() {
+		err = o.addHelmRepository()
+		if err != nil {
+			return err
+		}
+	} else {
+		err = o.findHelmBinary()
+		if err != nil {
+			return err
+		}
 	}
-	h := o.Helmer
-	_, err = helmer.AddHelmRepoIfMissing(h, helmer.JX3ChartRepository, "jxgh", "", "")
-	if err != nil {
-		return fmt.Errorf("failed to add JayeX github chart repository: %w", err)
+
+	if o.ChartVersion == "" {
+		o.ChartVersion, err = o.findChartVersion()
+		if err != nil {
+			return err
+		}
 	}
-	log.Logger().Debugf("updating helm repositories")
-	err = h.UpdateRepo()
+	err = o.verifyChart()
 	if err != nil {
-		log.Logger().Warnf("failed to update helm repositories: %s", err.Error())
+		return err
 	}
 
-	c := o.getCommandLine(o.HelmBin, o.GitURL)
+	if o.OutputDir != "" {
+		return o.renderManifests()
+	}
 
-	// lets sanitize and format the command line so it looks nicer in the console output
-	// TODO replace with c.CLI() when we switch to jx-helpers
-	commandLine := fmt.Sprintf("%s %s", c.Name, strings.Join(c.Args, " "))
-	token := o.GitToken
-	if o.GitURL != "" && token == "" {
-		u, err := url.Parse(o.GitURL)
-		if err == nil && u.User != nil {
-			token, _ = u.User.Password()
+	if o.Diff {
+		apply, err := o.showDiff()
+		if err != nil {
+			return fmt.Errorf("failed to diff the git operator: %w", err)
+		}
+		if !apply {
+			log.Logger().Infof("not applying the changes to the git operator")
+			return nil
 		}
 	}
-	if token != "" && !o.DryRun {
-		commandLine = strings.ReplaceAll(commandLine, token, "****")
+
+	err = o.ensureCredentialsSecret()
+	if err != nil {
+		return fmt.Errorf("failed to setup the git operator Secret: %w", err)
+	}
+	err = o.ensureCAConfigMap()
+	if err != nil {
+		return fmt.Errorf("failed to setup the git operator CA bundle: %w", err)
 	}
 
-	// lets split the command across lines
-	commandLine = strings.ReplaceAll(commandLine, " --set", " \\\n    --set")
+	c := o.getCommandLine(o.HelmBin, o.GitURL)
+	commandLine := o.formatCommandLine(c)
 
 	if o.DryRun {
 		log.Logger().Infof("\nTo install the git operator run this command:\n\n%s\n\n", termcolor.ColorInfo(commandLine))
 		return nil
 	}
 
-	log.Logger().Infof("running command:\n\n%s\n\n", termcolor.ColorInfo(commandLine))
+	if o.HelmSDK {
+		log.Logger().Infof("installing the git operator in process via the equivalent of:\n\n%s\n\n", termcolor.ColorInfo(commandLine))
 
-	_, err = o.CommandRunner(c)
-	if err != nil {
-		return fmt.Errorf("failed to run command %s: %w", commandLine, err)
+		status, err := o.HelmClient.UpgradeInstall(o.getReleaseOptions(o.GitURL))
+		if err != nil {
+			return fmt.Errorf("failed to install the git operator: %w", err)
+		}
+		log.Logger().Infof("release %s revision %s of chart %s version %s is %s", termcolor.ColorInfo(status.Name), termcolor.ColorInfo(status.Revision), termcolor.ColorInfo(status.Chart), termcolor.ColorInfo(status.ChartVersion), termcolor.ColorInfo(status.Status))
+	} else {
+		log.Logger().Infof("running command:\n\n%s\n\n", termcolor.ColorInfo(commandLine))
+
+		_, err = o.CommandRunner(c)
+		if err != nil {
+			return fmt.Errorf("failed to run command %s: %w", commandLine, err)
+		}
 	}
 
 	err = o.switchNamespace(o.Namespace)
@@ -218,6 +401,10 @@ func (o *Options) Run() error {
 		return nil
 	}
 	o.JobLogOptions.WaitMode = true
+	o.JobLogOptions.KubeConfig = o.KubeConfig
+	if o.JobLogOptions.Namespace == "" {
+		o.JobLogOptions.Namespace = o.Namespace
+	}
 	err = o.JobLogOptions.Run()
 	if err != nil {
 		return fmt.Errorf("failed to tail the JayeX boot Job pods: %w", err)
@@ -225,34 +412,96 @@ func (o *Options) Run() error {
 	return nil
 }
 
-func (o *Options) getCommandLine(helmBin, gitURL string) *cmdrunner.Command {
-	username := o.GitUserName
-	password := o.GitToken
+// runPreflight checks the cluster is ready for the git operator before we install it
+func (o *Options) runPreflight() error {
+	var err error
+	o.KubeClient, err = o.KubeConfig.LazyCreateKubeClient(o.KubeClient)
+	if err != nil {
+		return fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+	po := &preflight.Options{
+		Dir:        o.Dir,
+		Namespace:  o.Namespace,
+		KubeClient: o.KubeClient,
+	}
+	err = po.Run()
+	if err != nil {
+		return fmt.Errorf("the preflight checks failed. You can disable them via --skip-preflight: %w", err)
+	}
+	return nil
+}
 
-	args := []string{"upgrade", "--install"}
+// findHelmBinary lazily finds the helm binary
+func (o *Options) findHelmBinary() error {
+	var err error
+	if o.HelmBin == "" {
+		o.HelmBin, err = helmplugin.GetHelm3Binary()
+		if err != nil {
+			return err
+		}
+	}
+	if o.HelmBin == "" {
+		return fmt.Errorf("no helm binary found")
+	}
+	return nil
+}
+
+// addHelmRepository lazily creates the helmer and adds the helm repository for the git operator chart
+func (o *Options) addHelmRepository() error {
+	err := o.findHelmBinary()
+	if err != nil {
+		return err
+	}
 
-	if gitURL != "" {
-		args = append(args, "--set", fmt.Sprintf("url=%s", gitURL))
+	// lets add helm repository for jx-labs
+	if o.Helmer == nil {
+		o.Helmer = helmplugin.NewHelmer(o.HelmBin, o.Dir)
 	}
-	if username != "" {
-		args = append(args, "--set", fmt.Sprintf("username=%s", username))
+	h := o.Helmer
+	_, err = helmer.AddHelmRepoIfMissing(h, helmer.JX3ChartRepository, jxghRepoName, "", "")
+	if err != nil {
+		return fmt.Errorf("failed to add JayeX github chart repository: %w", err)
 	}
-	if password != "" {
-		args = append(args, "--set", fmt.Sprintf("password=%s", password))
+	log.Logger().Debugf("updating helm repositories")
+	err = h.UpdateRepo()
+	if err != nil {
+		log.Logger().Warnf("failed to update helm repositories: %s", err.Error())
 	}
-	for _, a := range o.HelmSetArgs {
-		args = append(args, "--set", a)
+	return nil
+}
+
+// formatCommandLine sanitizes and formats the command line so it looks nicer in the console output
+func (o *Options) formatCommandLine(c *cmdrunner.Command) string {
+	// TODO replace with c.CLI() when we switch to jx-helpers
+	commandLine := fmt.Sprintf("%s %s", c.Name, strings.Join(c.Args, " "))
+	token := o.GitToken
+	if o.GitURL != "" && token == "" {
+		u, err := url.Parse(o.GitURL)
+		if err == nil && u.User != nil {
+			token, _ = u.User.Password()
+		}
 	}
-	if len(o.GitSetupCommands) > 0 {
-		gitInitCommands := strings.Join(o.GitSetupCommands, "; ")
-		args = append(args, "--set", fmt.Sprintf("gitInitCommands=%s", gitInitCommands))
+	if token != "" && !o.DryRun {
+		commandLine = strings.ReplaceAll(commandLine, token, "****")
 	}
+
+	// lets split the command across lines
+	commandLine = strings.ReplaceAll(commandLine, " --set", " \\\n    --set")
+	return strings.ReplaceAll(commandLine, " --values", " \\\n    --values")
+}
+
+func (o *Options) getCommandLine(helmBin, gitURL string) *cmdrunner.Command {
+	args := []string{"upgrade", "--install"}
+
+	args = append(args, o.getValuesArgs(gitURL)...)
 	if o.ChartVersion != "" {
 		args = append(args, "--version", o.ChartVersion)
 	}
+	args = append(args, o.getVerifyArgs()...)
 	if o.Namespace != "" {
 		args = append(args, "--namespace", o.Namespace)
 	}
+	args = append(args, o.KubeConfig.HelmArgs()...)
 
 	if o.SkipNamespaceCreation {
 		args = append(args, o.ReleaseName, o.ChartName)
@@ -266,27 +515,176 @@ func (o *Options) getCommandLine(helmBin, gitURL string) *cmdrunner.Command {
 	}
 }
 
-// nolint
-func (o *Options) findChartVersion(_ *jxcore.RequirementsConfig) (string, error) {
-	/*
-		if o.ChartName == "" || o.ChartName[0] == '.' || o.ChartName[0] == '/' || o.ChartName[0] == '\\' || strings.Count(o.ChartName, "/") > 1 {
-			// relative chart folder so ignore version
-			return "", nil
+// getTemplateCommandLine returns the command to render the chart into the output directory or standard output
+func (o *Options) getTemplateCommandLine(helmBin, gitURL string) *cmdrunner.Command {
+	args := []string{"template"}
+
+	args = append(args, o.getValuesArgs(gitURL)...)
+	if o.ChartVersion != "" {
+		args = append(args, "--version", o.ChartVersion)
+	}
+	args = append(args, o.getVerifyArgs()...)
+	if o.Namespace != "" {
+		args = append(args, "--namespace", o.Namespace)
+	}
+	if o.OutputDir != "" {
+		args = append(args, "--output-dir", o.OutputDir)
+	}
+	args = append(args, o.ReleaseName, o.ChartName)
+
+	return &cmdrunner.Command{
+		Name: helmBin,
+		Args: args,
+	}
+}
+
+// getVerifyArgs returns the helm arguments to verify the provenance of the chart
+func (o *Options) getVerifyArgs() []string {
+	if !o.ChartVerify {
+		return nil
+	}
+	args := []string{"--verify"}
+	if o.ChartKeyring != "" {
+		args = append(args, "--keyring", o.ChartKeyring)
+	}
+	return args
+}
+
+// getValuesArgs returns the helm values file and set arguments for the chart
+func (o *Options) getValuesArgs(gitURL string) []string {
+	var args []string
+	for _, f := range o.ValuesFiles {
+		args = append(args, "--values", f)
+	}
+	for _, v := range o.getSetValues(gitURL) {
+		args = append(args, "--set", v)
+	}
+	return args
+}
+
+// getSetValues returns the helm set values for the chart
+func (o *Options) getSetValues(gitURL string) []string {
+	var answer []string
+	if o.passCredentialsToChart() {
+		if gitURL != "" {
+			answer = append(answer, fmt.Sprintf("url=%s", gitURL))
+		}
+		if o.GitUserName != "" {
+			answer = append(answer, fmt.Sprintf("username=%s", o.GitUserName))
+		}
+		if o.GitToken != "" {
+			answer = append(answer, fmt.Sprintf("password=%s", o.GitToken))
+		}
+	}
+	answer = append(answer, o.getNetworkSetValues()...)
+	answer = append(answer, o.HelmSetArgs...)
+	gitCommands := append(o.getNetworkGitCommands(), o.GitSetupCommands...)
+	if len(gitCommands) > 0 {
+		gitInitCommands := strings.Join(gitCommands, "; ")
+		answer = append(answer, fmt.Sprintf("gitInitCommands=%s", gitInitCommands))
+	}
+	return answer
+}
+
+// getReleaseOptions returns the options to install the chart in process
+func (o *Options) getReleaseOptions(gitURL string) *helmsdk.ReleaseOptions {
+	chartName := o.ChartName
+	repoURL := ""
+
+	// lets avoid depending on the local helm repository configuration
+	prefix := jxghRepoName + "/"
+	if strings.HasPrefix(chartName, prefix) {
+		chartName = strings.TrimPrefix(chartName, prefix)
+		repoURL = helmer.JX3ChartRepository
+	}
+	return &helmsdk.ReleaseOptions{
+		ReleaseName:     o.ReleaseName,
+		Namespace:       o.Namespace,
+		ChartName:       chartName,
+		ChartVersion:    o.ChartVersion,
+		RepoURL:         repoURL,
+		ValueFiles:      o.ValuesFiles,
+		SetValues:       o.getSetValues(gitURL),
+		Verify:          o.ChartVerify,
+		Keyring:         o.ChartKeyring,
+		CreateNamespace: !o.SkipNamespaceCreation,
+	}
+}
+
+// findChartVersion resolves the version of the git operator chart from the version stream
+func (o *Options) findChartVersion() (string, error) {
+	if o.ChartName == "" || !o.usesChartRepository() || strings.Count(o.ChartName, "/") > 1 {
+		// local or OCI chart so ignore the version stream
+		return "", nil
+	}
+
+	dir, cleanup, err := o.findVersionStreamDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the version stream: %w", err)
+	}
+	defer cleanup()
+	if dir == "" {
+		log.Logger().Debugf("no version stream found so using the latest version of chart %s", o.ChartName)
+		return "", nil
+	}
+	version, err := versionstream.LoadStableVersionNumber(dir, versionstream.KindChart, o.ChartName, true)
+	if err != nil {
+		return version, fmt.Errorf("failed to find version of chart %s in version stream %s: %w", o.ChartName, dir, err)
+	}
+	if version != "" {
+		log.Logger().Infof("using version %s of chart %s from the version stream", termcolor.ColorInfo(version), termcolor.ColorInfo(o.ChartName))
+	}
+	return version, nil
+}
+
+// findVersionStreamDir returns the directory of the version stream. If no version stream URL is specified
+// we default to the versionStream folder inside the cluster git repository. Otherwise the URL is either
+// a local directory or a git repository which is cloned at the optional ref. The returned func removes any clone
+func (o *Options) findVersionStreamDir() (string, func(), error) {
+	noCleanup := func() {}
+	u := o.VersionStreamURL
+	if u == "" {
+		dir := filepath.Join(o.Dir, "versionStream")
+		exists, err := files.DirExists(dir)
+		if err != nil {
+			return "", noCleanup, fmt.Errorf("failed to check if dir %s exists: %w", dir, err)
+		}
+		if exists {
+			return dir, noCleanup, nil
 		}
+		return "", noCleanup, nil
+	}
 
-		f := clients.NewFactory()
-		co := opts.NewCommonOptionsWithTerm(f, os.Stdin, os.Stdout, os.Stderr)
-		co.BatchMode = o.BatchMode
+	exists, err := files.DirExists(u)
+	if err != nil {
+		return "", noCleanup, fmt.Errorf("failed to check if dir %s exists: %w", u, err)
+	}
+	if exists {
+		return u, noCleanup, nil
+	}
 
-		u := req.VersionStream.URL
-		ref := req.VersionStream.Ref
-		version, err := versions.GetVersionNumber(versionstream.KindChart, o.ChartName, u, ref, o.Git(), co.GetIOFileHandles())
+	if o.Gitter == nil {
+		o.Gitter = cli.NewCLIClient("", o.CommandRunner)
+	}
+	dir, err := gitclient.CloneToDir(o.Gitter, u, "")
+	if err != nil {
+		return "", noCleanup, fmt.Errorf("failed to clone version stream %s: %w", u, err)
+	}
+	cleanup := func() {
+		err := os.RemoveAll(dir)
 		if err != nil {
-			return version, errors.Wrapf(err, "failed to find version of chart %s in version stream %s ref %s", o.ChartName, u, ref)
+			log.Logger().Warnf("failed to remove the clone of version stream %s in %s: %s", u, dir, err.Error())
 		}
-		return version, nil
-	*/
-	return "", nil
+	}
+	ref := o.VersionStreamRef
+	if ref != "" {
+		err = gitclient.Checkout(o.Gitter, dir, ref)
+		if err != nil {
+			cleanup()
+			return "", noCleanup, fmt.Errorf("failed to checkout ref %s of version stream %s: %w", ref, u, err)
+		}
+	}
+	return dir, cleanup, nil
 }
 
 func (o *Options) ensureValidGitURL(gitURL string) (string, error) {
@@ -357,30 +755,62 @@ func (o *Options) ensureValidGitURL(gitURL string) (string, error) {
 }
 
 func (o *Options) switchNamespace(ns string) error {
+	if o.CreateContext {
+		name, err := o.KubeConfig.CreateContext(ns)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			log.Logger().Warnf("there is no context defined in your Kubernetes configuration so cannot create a context for namespace %s - we may be inside a test case or pod?", ns)
+			return nil
+		}
+		log.Logger().Infof("switched to the context %s using namespace %s so that you can start to create or import projects into JayeX: https://jayex.io/v3/develop/create-project/", termcolor.ColorInfo(name), termcolor.ColorInfo(ns))
+		return nil
+	}
 	if o.NoSwitchNamespace {
 		log.Logger().Infof("disabled switching namespace. Please make sure you are in the %s namespace when you try to create or import a project", ns)
 		return nil
 	}
-	cfg, pathOptions, err := kubeclient.LoadConfig()
+	found, err := o.KubeConfig.SwitchNamespace(ns)
 	if err != nil {
-		return fmt.Errorf("loading Kubernetes configuration: %w", err)
+		return err
 	}
-	ctx := kube.CurrentContext(cfg)
-	if ctx == nil {
+	if !found {
 		log.Logger().Warnf("there is no context defined in your Kubernetes configuration so cannot change to namepace %s - we may be inside a test case or pod?", ns)
 		return nil
 	}
+	log.Logger().Infof("switched to namespace %s so that you can start to create or import projects into JayeX: https://jayex.io/v3/develop/create-project/", termcolor.ColorInfo(ns))
+	return nil
+}
 
-	if ctx.Namespace == ns {
-		return nil
+// confirmCluster displays the cluster the git operator is about to be installed into and asks the user to confirm it
+// unless in batch mode or the standard input is not a terminal. Returns false if the user does not want to continue
+func (o *Options) confirmCluster() (bool, error) {
+	if o.KubeClient != nil || o.DryRun {
+		// the kubernetes client has been supplied so there is no kube config to describe
+		return true, nil
 	}
-	ctx.Namespace = ns
-	err = clientcmd.ModifyConfig(pathOptions, *cfg, false)
+	contextName, server, err := o.KubeConfig.Server()
 	if err != nil {
-		return fmt.Errorf("failed to update the kube config to namepace %s: %w", ns, err)
+		return false, fmt.Errorf("failed to find the cluster to install the git operator into: %w", err)
 	}
-	log.Logger().Infof("switched to namespace %s so that you can start to create or import projects into JayeX: https://jayex.io/v3/develop/create-project/", termcolor.ColorInfo(ns))
-	return nil
+	if server == "" {
+		// there is no kube config such as when running inside a pod
+		return true, nil
+	}
+	log.Logger().Infof("installing the git operator into namespace %s of cluster %s using context %s", termcolor.ColorInfo(o.Namespace), termcolor.ColorInfo(server), termcolor.ColorInfo(contextName))
+	if o.BatchMode {
+		return true, nil
+	}
+	if o.Input == nil {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			// there is no user to ask such as when running in a pipeline or a test
+			log.Logger().Warnf("not asking to confirm the cluster as the standard input is not a terminal. Use --batch-mode to avoid this warning")
+			return true, nil
+		}
+		o.Input = survey.NewInput()
+	}
+	return o.Input.Confirm(fmt.Sprintf("Do you want to install the git operator into cluster %s?", server), false, "use --context or --kubeconfig to choose a different cluster")
 }
 
 func findGitURLFromDir(dir string) (string, error) {
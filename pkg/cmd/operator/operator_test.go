@@ -1,12 +1,34 @@
 package operator_test
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/jenkins-x-plugins/jx-admin/pkg/bootjobs"
 	"github.com/jenkins-x-plugins/jx-admin/pkg/cmd/operator"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/helmsdk"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/operatorsecrets"
+	"github.com/jenkins-x/go-scm/scm"
+	fakescm "github.com/jenkins-x/go-scm/scm/driver/fake"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner/fakerunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/helmer"
+	fakeinput "github.com/jenkins-x/jx-helpers/v3/pkg/input/fake"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 func TestOperator(t *testing.T) {
@@ -35,13 +57,16 @@ func TestOperator(t *testing.T) {
 		runner := &fakerunner.FakeRunner{}
 
 		_, o := operator.NewCmdOperator()
+		o.SkipPreflight = true
 		o.CommandRunner = runner.Run
 		o.HelmBin = "helm"
 		o.GitUserName = tc.userName
 		o.GitToken = "fakegittoken"
 		o.GitURL = "https://github.com/jx3-gitops-repositories/jx3-kubernetes"
+		o.ScmClient = newFakeScmClient()
 		o.Helmer = helmer.NewFakeHelmer()
 		o.NoLog = true
+		o.BatchMode = true
 		if tc.skipcreatens {
 			o.SkipNamespaceCreation = true
 		}
@@ -55,3 +80,629 @@ func TestOperator(t *testing.T) {
 		}
 	}
 }
+
+func TestOperatorChartVersionFromVersionStream(t *testing.T) {
+	runner := &fakerunner.FakeRunner{}
+
+	_, o := operator.NewCmdOperator()
+	o.SkipPreflight = true
+	o.CommandRunner = runner.Run
+	o.HelmBin = "helm"
+	o.Dir = filepath.Join("test_data", "version-stream")
+	o.GitUserName = "fakegitusername"
+	o.GitToken = "fakegittoken"
+	o.GitURL = "https://github.com/jx3-gitops-repositories/jx3-kubernetes"
+	o.ScmClient = newFakeScmClient()
+	o.Helmer = helmer.NewFakeHelmer()
+	o.NoLog = true
+	o.NoSwitchNamespace = true
+	o.BatchMode = true
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run the operator")
+
+	assert.Equal(t, "0.1.2", o.ChartVersion, "chart version resolved from the version stream")
+	require.Len(t, runner.OrderedCommands, 1, "commands")
+	assert.Contains(t, cmdrunner.CLI(runner.OrderedCommands[0]), "--version 0.1.2", "helm command line")
+}
+
+func TestOperatorChartVersionFromClonedVersionStream(t *testing.T) {
+	runner := &fakerunner.FakeRunner{}
+	cloneDir := ""
+
+	_, o := operator.NewCmdOperator()
+	o.SkipPreflight = true
+	o.CommandRunner = func(c *cmdrunner.Command) (string, error) {
+		if c.Name == "git" && len(c.Args) == 3 && c.Args[0] == "clone" {
+			// lets fake the clone of the version stream
+			cloneDir = c.Args[2]
+			return "", files.CopyDirOverwrite(filepath.Join("test_data", "version-stream", "versionStream"), cloneDir)
+		}
+		return runner.Run(c)
+	}
+	o.HelmBin = "helm"
+	o.VersionStreamURL = "https://github.com/jenkins-x/jx3-versions.git"
+	o.GitUserName = "fakegitusername"
+	o.GitToken = "fakegittoken"
+	o.GitURL = "https://github.com/jx3-gitops-repositories/jx3-kubernetes"
+	o.ScmClient = newFakeScmClient()
+	o.Helmer = helmer.NewFakeHelmer()
+	o.NoLog = true
+	o.NoSwitchNamespace = true
+	o.BatchMode = true
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run the operator")
+
+	assert.Equal(t, "0.1.2", o.ChartVersion, "chart version resolved from the version stream")
+	require.NotEmpty(t, cloneDir, "should have cloned the version stream")
+	assert.NoDirExists(t, cloneDir, "should have removed the clone of the version stream")
+}
+
+func TestOperatorHelmSDK(t *testing.T) {
+	runner := &fakerunner.FakeRunner{}
+	helmClient := helmsdk.NewFakeClient()
+
+	_, o := operator.NewCmdOperator()
+	o.SkipPreflight = true
+	o.CommandRunner = runner.Run
+	o.HelmSDK = true
+	o.HelmClient = helmClient
+	o.GitUserName = "fakegitusername"
+	o.GitToken = "fakegittoken"
+	o.GitURL = "https://github.com/jx3-gitops-repositories/jx3-kubernetes"
+	o.ScmClient = newFakeScmClient()
+	o.ChartVersion = "1.2.3"
+	o.NoLog = true
+	o.NoSwitchNamespace = true
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run the operator")
+
+	runner.ExpectResults(t)
+	require.Len(t, helmClient.Installs, 1, "installs")
+	ro := helmClient.Installs[0]
+	assert.Equal(t, "jxgo", ro.ReleaseName, "ReleaseName")
+	assert.Equal(t, "jx-git-operator", ro.Namespace, "Namespace")
+	assert.Equal(t, "jx-git-operator", ro.ChartName, "ChartName")
+	assert.Equal(t, helmer.JX3ChartRepository, ro.RepoURL, "RepoURL")
+	assert.Equal(t, "1.2.3", ro.ChartVersion, "ChartVersion")
+	assert.True(t, ro.CreateNamespace, "CreateNamespace")
+	assert.Equal(t, []string{"url=https://github.com/jx3-gitops-repositories/jx3-kubernetes", "username=fakegitusername", "password=fakegittoken"}, ro.SetValues, "SetValues")
+}
+
+func TestOperatorSecretModeKube(t *testing.T) {
+	runner := &fakerunner.FakeRunner{}
+	kubeClient := fake.NewSimpleClientset()
+
+	_, o := operator.NewCmdOperator()
+	o.SkipPreflight = true
+	o.CommandRunner = runner.Run
+	o.HelmBin = "helm"
+	o.Helmer = helmer.NewFakeHelmer()
+	o.KubeClient = kubeClient
+	o.SecretMode = operator.SecretModeKube
+	o.GitUserName = "fakegitusername"
+	o.GitToken = "fakegittoken"
+	o.GitURL = "https://github.com/jx3-gitops-repositories/jx3-kubernetes"
+	o.ScmClient = newFakeScmClient()
+	o.ChartVersion = "1.2.3"
+	o.NoLog = true
+	o.NoSwitchNamespace = true
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run the operator")
+
+	runner.ExpectResults(t, fakerunner.FakeResult{
+		CLI: "helm upgrade --install --version 1.2.3 --namespace jx-git-operator --create-namespace jxgo jxgh/jx-git-operator",
+	})
+
+	secret, err := kubeClient.CoreV1().Secrets("jx-git-operator").Get(context.TODO(), operatorsecrets.DefaultSecretName, metav1.GetOptions{})
+	require.NoError(t, err, "failed to find the git operator Secret")
+	creds := operatorsecrets.GetCredentials(secret)
+	assert.Equal(t, "https://github.com/jx3-gitops-repositories/jx3-kubernetes", creds.URL, "url")
+	assert.Equal(t, "fakegitusername", creds.Username, "username")
+	assert.Equal(t, "fakegittoken", creds.Password, "password")
+	assert.Equal(t, bootjobs.GitOperatorKind, secret.Labels[bootjobs.LabelGitOperatorKind], "label")
+}
+
+func TestOperatorSecretModeKubeReplacesHelmSecret(t *testing.T) {
+	runner := &fakerunner.FakeRunner{}
+	secret := operatorsecrets.NewSecret("jx-git-operator", operatorsecrets.DefaultSecretName, &operatorsecrets.Credentials{
+		URL:      "https://github.com/jx3-gitops-repositories/jx3-kubernetes",
+		Username: "oldgitusername",
+		Password: "oldgittoken",
+	})
+	secret.Labels["app.kubernetes.io/managed-by"] = "Helm"
+	secret.Annotations = map[string]string{
+		"meta.helm.sh/release-name":      "jxgo",
+		"meta.helm.sh/release-namespace": "jx-git-operator",
+	}
+	secret.Data["stale"] = []byte("value")
+	kubeClient := fake.NewSimpleClientset(secret)
+
+	_, o := operator.NewCmdOperator()
+	o.SkipPreflight = true
+	o.CommandRunner = runner.Run
+	o.HelmBin = "helm"
+	o.Helmer = helmer.NewFakeHelmer()
+	o.KubeClient = kubeClient
+	o.SecretMode = operator.SecretModeKube
+	o.GitUserName = "fakegitusername"
+	o.GitToken = "fakegittoken"
+	o.GitURL = "https://github.com/jx3-gitops-repositories/jx3-kubernetes"
+	o.ScmClient = newFakeScmClient()
+	o.ChartVersion = "1.2.3"
+	o.NoLog = true
+	o.NoSwitchNamespace = true
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run the operator")
+
+	secret, err = kubeClient.CoreV1().Secrets("jx-git-operator").Get(context.TODO(), operatorsecrets.DefaultSecretName, metav1.GetOptions{})
+	require.NoError(t, err, "failed to find the git operator Secret")
+	assert.Equal(t, "fakegittoken", operatorsecrets.GetCredentials(secret).Password, "password")
+	assert.NotContains(t, secret.Data, "stale", "should have replaced the data")
+	assert.NotContains(t, secret.Labels, "app.kubernetes.io/managed-by", "labels")
+	assert.NotContains(t, secret.Annotations, "meta.helm.sh/release-name", "annotations")
+	assert.Equal(t, "keep", secret.Annotations["helm.sh/resource-policy"], "resource policy")
+	assert.Equal(t, bootjobs.GitOperatorKind, secret.Labels[bootjobs.LabelGitOperatorKind], "label")
+}
+
+func TestOperatorOutputDir(t *testing.T) {
+	runner := &fakerunner.FakeRunner{}
+	outDir := t.TempDir()
+
+	_, o := operator.NewCmdOperator()
+	o.SkipPreflight = true
+	o.CommandRunner = runner.Run
+	o.HelmBin = "helm"
+	o.Helmer = helmer.NewFakeHelmer()
+	o.SecretMode = operator.SecretModeKube
+	o.GitUserName = "fakegitusername"
+	o.GitToken = "fakegittoken"
+	o.GitURL = "https://github.com/jx3-gitops-repositories/jx3-kubernetes"
+	o.ScmClient = newFakeScmClient()
+	o.ChartVersion = "1.2.3"
+	o.ValuesFiles = []string{"my-values.yaml"}
+	o.OutputDir = outDir
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run the operator")
+
+	runner.ExpectResults(t, fakerunner.FakeResult{
+		CLI: "helm template --values my-values.yaml --version 1.2.3 --namespace jx-git-operator --output-dir " + outDir + " jxgo jxgh/jx-git-operator",
+	})
+
+	assert.FileExists(t, filepath.Join(outDir, operatorsecrets.DefaultSecretName+"-secret.yaml"), "should have saved the git operator Secret")
+}
+
+func TestOperatorOutputDirHelmSecretMode(t *testing.T) {
+	runner := &fakerunner.FakeRunner{}
+
+	_, o := operator.NewCmdOperator()
+	o.SkipPreflight = true
+	o.CommandRunner = runner.Run
+	o.HelmBin = "helm"
+	o.Helmer = helmer.NewFakeHelmer()
+	o.SecretMode = operator.SecretModeHelm
+	o.GitUserName = "fakegitusername"
+	o.GitToken = "fakegittoken"
+	o.GitURL = "https://github.com/jx3-gitops-repositories/jx3-kubernetes"
+	o.ScmClient = newFakeScmClient()
+	o.ChartVersion = "1.2.3"
+	o.OutputDir = t.TempDir()
+
+	err := o.Run()
+	require.Error(t, err, "should not render the git token in plain text")
+	assert.Contains(t, err.Error(), "secret-mode", "error")
+	assert.Empty(t, runner.OrderedCommands, "should not have rendered the chart")
+}
+
+func TestOperatorLocalChart(t *testing.T) {
+	dir := t.TempDir()
+	chart := filepath.Join(dir, "jx-git-operator-0.1.2.tgz")
+	data := []byte("not really a chart")
+	err := os.WriteFile(chart, data, 0o600)
+	require.NoError(t, err, "failed to save chart")
+
+	testCases := []struct {
+		description string
+		checksum    string
+		expectError bool
+	}{
+		{
+			description: "valid checksum",
+			checksum:    fmt.Sprintf("%x  jx-git-operator-0.1.2.tgz\n", sha256.Sum256(data)),
+		},
+		{
+			description: "invalid checksum",
+			checksum:    fmt.Sprintf("%x  jx-git-operator-0.1.2.tgz\n", sha256.Sum256([]byte("something else"))),
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		checksumFile := filepath.Join(dir, "checksum.txt")
+		err = os.WriteFile(checksumFile, []byte(tc.checksum), 0o600)
+		require.NoError(t, err, "failed to save checksum file")
+
+		runner := &fakerunner.FakeRunner{}
+		_, o := operator.NewCmdOperator()
+		o.SkipPreflight = true
+		o.CommandRunner = runner.Run
+		o.HelmBin = "helm"
+		o.ChartName = chart
+		o.ChartChecksumFile = checksumFile
+		o.SecretMode = operator.SecretModeExisting
+		o.KubeClient = newFakeKubeClientWithSecret()
+		o.NoLog = true
+		o.NoSwitchNamespace = true
+
+		err = o.Run()
+		if tc.expectError {
+			require.Error(t, err, "should have failed for %s", tc.description)
+			assert.Contains(t, err.Error(), "does not match", "error message for %s", tc.description)
+			runner.ExpectResults(t)
+			continue
+		}
+		require.NoError(t, err, "failed to run the operator for %s", tc.description)
+
+		assert.Nil(t, o.Helmer, "should not have added a helm repository for %s", tc.description)
+		runner.ExpectResults(t, fakerunner.FakeResult{
+			CLI: "helm upgrade --install --namespace jx-git-operator --create-namespace jxgo " + chart,
+		})
+	}
+}
+
+func TestOperatorOCIChart(t *testing.T) {
+	helmClient := helmsdk.NewFakeClient()
+
+	_, o := operator.NewCmdOperator()
+	o.SkipPreflight = true
+	o.HelmSDK = true
+	o.HelmClient = helmClient
+	o.ChartName = "oci://registry.example.com/charts/jx-git-operator"
+	o.ChartVersion = "0.1.2"
+	o.ChartVerify = true
+	o.SecretMode = operator.SecretModeExisting
+	o.KubeClient = newFakeKubeClientWithSecret()
+	o.NoLog = true
+	o.NoSwitchNamespace = true
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run the operator")
+
+	assert.Nil(t, o.Helmer, "should not have added a helm repository")
+	require.Len(t, helmClient.Installs, 1, "installs")
+	ro := helmClient.Installs[0]
+	assert.Equal(t, "oci://registry.example.com/charts/jx-git-operator", ro.ChartName, "ChartName")
+	assert.Equal(t, "", ro.RepoURL, "RepoURL")
+	assert.Equal(t, "0.1.2", ro.ChartVersion, "ChartVersion")
+	assert.True(t, ro.Verify, "Verify")
+}
+
+func TestOperatorCAFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600)
+	require.NoError(t, err, "failed to save CA file")
+
+	// lets restore the environment variable if it is modified
+	t.Setenv("GIT_SSL_CAINFO", "")
+
+	runner := &fakerunner.FakeRunner{}
+	kubeClient := newFakeKubeClientWithSecret()
+
+	_, o := operator.NewCmdOperator()
+	o.SkipPreflight = true
+	o.CommandRunner = runner.Run
+	o.HelmBin = "helm"
+	o.Helmer = helmer.NewFakeHelmer()
+	o.KubeClient = kubeClient
+	o.SecretMode = operator.SecretModeExisting
+	o.Network.CAFile = caFile
+	o.ChartVersion = "1.2.3"
+	o.NoLog = true
+	o.NoSwitchNamespace = true
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the operator")
+
+	cm, err := kubeClient.CoreV1().ConfigMaps("jx-git-operator").Get(context.TODO(), operator.CAConfigMapName, metav1.GetOptions{})
+	require.NoError(t, err, "failed to find the CA ConfigMap")
+	assert.Contains(t, cm.Data["ca.crt"], "BEGIN CERTIFICATE", "ConfigMap CA")
+
+	require.Len(t, runner.OrderedCommands, 1, "commands")
+	commandLine := cmdrunner.CLI(runner.OrderedCommands[0])
+	assert.Contains(t, commandLine, "--set extraVolumes[0].configMap.name="+operator.CAConfigMapName, "helm command line")
+	assert.Contains(t, commandLine, "--set gitInitCommands=git config --global http.sslCAInfo /etc/jx-git-operator/ca/ca.crt", "helm command line")
+
+	// the CA should only be used by the HTTP client for the git server and not the whole process
+	require.NotNil(t, o.HTTPClient, "HTTPClient")
+	resp, err := o.HTTPClient.Get(server.URL)
+	require.NoError(t, err, "failed to access the TLS server with the CA")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "response status")
+
+	_, err = http.Get(server.URL)
+	assert.Error(t, err, "the default HTTP client should not trust the CA")
+	assert.Empty(t, os.Getenv("GIT_SSL_CAINFO"), "the environment should not be modified")
+}
+
+func TestOperatorCreateContext(t *testing.T) {
+	kubeConfigFile := filepath.Join(t.TempDir(), "config")
+	err := os.WriteFile(kubeConfigFile, []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: my-cluster
+  cluster:
+    server: https://my-cluster.example.com
+- name: other-cluster
+  cluster:
+    server: https://other-cluster.example.com
+users:
+- name: admin
+  user:
+    token: dummy
+contexts:
+- name: my-cluster-admin
+  context:
+    cluster: my-cluster
+    user: admin
+    namespace: default
+- name: other
+  context:
+    cluster: other-cluster
+    user: admin
+current-context: other
+`), 0o600)
+	require.NoError(t, err, "failed to save kube config")
+
+	runner := &fakerunner.FakeRunner{}
+
+	_, o := operator.NewCmdOperator()
+	o.SkipPreflight = true
+	o.CommandRunner = runner.Run
+	o.HelmBin = "helm"
+	o.Helmer = helmer.NewFakeHelmer()
+	o.KubeClient = newFakeKubeClientWithSecret()
+	o.SecretMode = operator.SecretModeExisting
+	o.ChartVersion = "1.2.3"
+	o.KubeConfig.File = kubeConfigFile
+	o.KubeConfig.Context = "my-cluster-admin"
+	o.CreateContext = true
+	o.NoLog = true
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the operator")
+
+	require.Len(t, runner.OrderedCommands, 1, "commands")
+	commandLine := cmdrunner.CLI(runner.OrderedCommands[0])
+	assert.Contains(t, commandLine, "--kube-context my-cluster-admin --kubeconfig "+kubeConfigFile, "helm command line")
+
+	config, err := clientcmd.LoadFromFile(kubeConfigFile)
+	require.NoError(t, err, "failed to load the kube config")
+	assert.Equal(t, "jx-my-cluster", config.CurrentContext, "current context")
+	require.NotNil(t, config.Contexts["jx-my-cluster"], "should have created the context")
+	assert.Equal(t, "my-cluster", config.Contexts["jx-my-cluster"].Cluster, "cluster of the new context")
+	assert.Equal(t, "jx-git-operator", config.Contexts["jx-my-cluster"].Namespace, "namespace of the new context")
+	assert.Equal(t, "default", config.Contexts["my-cluster-admin"].Namespace, "should not have changed the namespace of the existing context")
+}
+
+func TestOperatorDiff(t *testing.T) {
+	testCases := []struct {
+		answer        string
+		expectInstall bool
+	}{
+		{
+			answer:        "no",
+			expectInstall: false,
+		},
+		{
+			answer:        "yes",
+			expectInstall: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		helmClient := helmsdk.NewFakeClient()
+		helmClient.AddDeployed(&helmsdk.Release{
+			ReleaseStatus: helmsdk.ReleaseStatus{
+				Name:      "jxgo",
+				Namespace: "jx-git-operator",
+			},
+			Manifest: "---\n# Source: jx-git-operator/templates/deployment.yaml\nimage: jx-git-operator:0.1.1\n",
+			Values: map[string]interface{}{
+				"url":      "https://github.com/jx3-gitops-repositories/jx3-kubernetes",
+				"username": "fakegitusername",
+				"password": "oldgittoken",
+			},
+		})
+		helmClient.Manifest = "---\n# Source: jx-git-operator/templates/deployment.yaml\nimage: jx-git-operator:0.1.2\n"
+		out := &bytes.Buffer{}
+
+		_, o := operator.NewCmdOperator()
+		o.SkipPreflight = true
+		o.HelmSDK = true
+		o.HelmClient = helmClient
+		o.Diff = true
+		o.Out = out
+		o.KubeConfig.File = filepath.Join("test_data", "kubeconfig.yaml")
+		// confirm the cluster then answer whether to apply the diff
+		o.Input = &fakeinput.FakeInput{OrderedValues: []string{"yes", tc.answer}}
+		o.GitUserName = "fakegitusername"
+		o.GitToken = "newgittoken"
+		o.GitURL = "https://github.com/jx3-gitops-repositories/jx3-kubernetes"
+		o.ScmClient = newFakeScmClient()
+		o.ChartVersion = "0.1.2"
+		o.NoLog = true
+		o.NoSwitchNamespace = true
+
+		err := o.Run()
+		require.NoError(t, err, "failed to run the operator for answer %s", tc.answer)
+
+		text := out.String()
+		t.Logf("diff for answer %s:\n%s\n", tc.answer, text)
+		assert.Contains(t, text, "-image: jx-git-operator:0.1.1", "diff for answer %s", tc.answer)
+		assert.Contains(t, text, "+image: jx-git-operator:0.1.2", "diff for answer %s", tc.answer)
+		assert.NotContains(t, text, "oldgittoken", "diff for answer %s", tc.answer)
+		assert.NotContains(t, text, "newgittoken", "diff for answer %s", tc.answer)
+		require.Len(t, helmClient.Templates, 1, "templates for answer %s", tc.answer)
+		assert.True(t, helmClient.Templates[0].Validate, "should render against the cluster for answer %s", tc.answer)
+		assert.True(t, helmClient.Templates[0].IsUpgrade, "should render as an upgrade for answer %s", tc.answer)
+		if tc.expectInstall {
+			assert.Len(t, helmClient.Installs, 1, "installs for answer %s", tc.answer)
+		} else {
+			assert.Empty(t, helmClient.Installs, "installs for answer %s", tc.answer)
+		}
+	}
+}
+
+func TestOperatorDiffHelmCLI(t *testing.T) {
+	runner := &fakerunner.FakeRunner{
+		CommandRunner: func(c *cmdrunner.Command) (string, error) {
+			switch {
+			case len(c.Args) > 1 && c.Args[0] == "get" && c.Args[1] == "manifest":
+				return "---\nimage: jx-git-operator:0.1.1\n", nil
+			case len(c.Args) > 1 && c.Args[0] == "get" && c.Args[1] == "values":
+				return "url: https://github.com/jx3-gitops-repositories/jx3-kubernetes\n", nil
+			case len(c.Args) > 0 && c.Args[0] == "template":
+				return "---\nimage: jx-git-operator:0.1.2\n", nil
+			}
+			return "", nil
+		},
+	}
+	out := &bytes.Buffer{}
+
+	_, o := operator.NewCmdOperator()
+	o.SkipPreflight = true
+	o.CommandRunner = runner.Run
+	o.HelmBin = "helm"
+	o.Diff = true
+	o.Out = out
+	o.BatchMode = true
+	o.KubeConfig.File = filepath.Join("test_data", "kubeconfig.yaml")
+	o.GitUserName = "fakegitusername"
+	o.GitToken = "fakegittoken"
+	o.GitURL = "https://github.com/jx3-gitops-repositories/jx3-kubernetes"
+	o.ScmClient = newFakeScmClient()
+	o.ChartVersion = "0.1.2"
+	o.Helmer = helmer.NewFakeHelmer()
+	o.NoLog = true
+	o.NoSwitchNamespace = true
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run the operator")
+
+	assert.Contains(t, out.String(), "+image: jx-git-operator:0.1.2", "diff")
+	var templateCLI string
+	for _, c := range runner.OrderedCommands {
+		if len(c.Args) > 0 && c.Args[0] == "template" {
+			templateCLI = cmdrunner.CLI(c)
+		}
+	}
+	// the chart is rendered against the cluster in the same way as the deployed release
+	assert.Contains(t, templateCLI, "--validate --is-upgrade --kubeconfig "+o.KubeConfig.File, "helm template command")
+}
+
+func TestOperatorVerifyGitCredentialsRepositoryNotFound(t *testing.T) {
+	runner := &fakerunner.FakeRunner{}
+
+	_, o := operator.NewCmdOperator()
+	o.SkipPreflight = true
+	o.CommandRunner = runner.Run
+	o.HelmBin = "helm"
+	o.Helmer = helmer.NewFakeHelmer()
+	o.GitUserName = "fakegitusername"
+	o.GitToken = "fakegittoken"
+	o.GitURL = "https://github.com/jx3-gitops-repositories/does-not-exist"
+	o.ScmClient = newFakeScmClient()
+	o.NoLog = true
+	o.NoSwitchNamespace = true
+
+	err := o.Run()
+	require.Error(t, err, "should have failed to verify the git credentials")
+	assert.Contains(t, err.Error(), "404", "error message")
+
+	runner.ExpectResults(t)
+}
+
+func TestOperatorGitHubApp(t *testing.T) {
+	runner := &fakerunner.FakeRunner{}
+	kubeClient := fake.NewSimpleClientset()
+
+	_, o := operator.NewCmdOperator()
+	o.SkipPreflight = true
+	o.CommandRunner = runner.Run
+	o.HelmBin = "helm"
+	o.Helmer = helmer.NewFakeHelmer()
+	o.KubeClient = kubeClient
+	o.SecretMode = operator.SecretModeKube
+	o.GitURL = "https://github.com/jx3-gitops-repositories/jx3-kubernetes"
+	o.ScmClient = newFakeScmClient()
+	o.GitHubAppID = "123"
+	o.GitHubAppInstallationID = "456"
+	o.GitHubAppPrivateKeyFile = "app.private-key.pem"
+	o.ChartVersion = "1.2.3"
+	o.NoLog = true
+	o.NoSwitchNamespace = true
+
+	err := o.Run()
+	require.Error(t, err, "should not support GitHub Apps")
+	assert.Contains(t, err.Error(), "GitHub App authentication is not supported", "error")
+	assert.Empty(t, runner.OrderedCommands, "should not have installed the git operator")
+}
+
+func TestOperatorSSHURL(t *testing.T) {
+	for _, gitURL := range []string{
+		"git@github.com:jx3-gitops-repositories/jx3-kubernetes.git",
+		"ssh://git@github.com:2222/jx3-gitops-repositories/jx3-kubernetes.git",
+	} {
+		runner := &fakerunner.FakeRunner{}
+		kubeClient := fake.NewSimpleClientset()
+
+		_, o := operator.NewCmdOperator()
+		o.SkipPreflight = true
+		o.CommandRunner = runner.Run
+		o.HelmBin = "helm"
+		o.Helmer = helmer.NewFakeHelmer()
+		o.KubeClient = kubeClient
+		o.SecretMode = operator.SecretModeKube
+		o.GitUserName = "fakegitusername"
+		o.GitToken = "fakegittoken"
+		o.GitURL = gitURL
+		o.ScmClient = newFakeScmClient()
+		o.ChartVersion = "1.2.3"
+		o.NoLog = true
+		o.NoSwitchNamespace = true
+
+		err := o.Run()
+		require.Error(t, err, "should not support the SSH git URL %s", gitURL)
+		assert.Contains(t, err.Error(), "SSH git URLs are not supported", "error for %s", gitURL)
+		assert.Empty(t, runner.OrderedCommands, "should not have installed the git operator for %s", gitURL)
+
+		_, err = kubeClient.CoreV1().Secrets("jx-git-operator").Get(context.TODO(), operatorsecrets.DefaultSecretName, metav1.GetOptions{})
+		assert.True(t, apierrors.IsNotFound(err), "should not have created the git operator Secret for %s", gitURL)
+	}
+}
+
+func newFakeScmClient() *scm.Client {
+	scmClient, fakeData := fakescm.NewDefault()
+	fakeData.Repositories = append(fakeData.Repositories, &scm.Repository{
+		Namespace: "jx3-gitops-repositories",
+		Name:      "jx3-kubernetes",
+		FullName:  "jx3-gitops-repositories/jx3-kubernetes",
+	})
+	return scmClient
+}
+
+// newFakeKubeClientWithSecret returns a fake kubernetes client containing an existing git operator Secret
+func newFakeKubeClientWithSecret() *fake.Clientset {
+	return fake.NewSimpleClientset(operatorsecrets.NewSecret("jx-git-operator", operatorsecrets.DefaultSecretName, &operatorsecrets.Credentials{
+		URL:      "https://github.com/jx3-gitops-repositories/jx3-kubernetes",
+		Username: "fakegitusername",
+		Password: "fakegittoken",
+	}))
+}
@@ -0,0 +1,112 @@
+package operator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/operatorsecrets"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+)
+
+const sourcePrefix = "# Source: "
+
+// renderManifests renders the git operator manifests into the output directory rather than installing them
+func (o *Options) renderManifests() error {
+	dir := o.OutputDir
+	if o.passCredentialsToChart() && o.GitToken != "" {
+		// the chart would render the git token in plain text into its Secret
+		return options.InvalidOptionf("secret-mode", o.SecretMode, "the git token would be saved in plain text in the rendered manifests in %s so please use --secret-mode %s or %s", dir, SecretModeKube, SecretModeExisting)
+	}
+	if o.DryRun {
+		c := o.getTemplateCommandLine(o.HelmBin, o.GitURL)
+		log.Logger().Infof("\nTo render the git operator run this command:\n\n%s\n\n", termcolor.ColorInfo(o.formatCommandLine(c)))
+		return nil
+	}
+
+	err := os.MkdirAll(dir, files.DefaultDirWritePermissions)
+	if err != nil {
+		return fmt.Errorf("failed to create output dir %s: %w", dir, err)
+	}
+
+	if o.HelmSDK {
+		manifest, err := o.HelmClient.Template(o.getReleaseOptions(o.GitURL))
+		if err != nil {
+			return fmt.Errorf("failed to render the git operator chart: %w", err)
+		}
+		err = writeManifests(dir, manifest)
+		if err != nil {
+			return err
+		}
+	} else {
+		c := o.getTemplateCommandLine(o.HelmBin, o.GitURL)
+		log.Logger().Infof("running command:\n\n%s\n\n", termcolor.ColorInfo(o.formatCommandLine(c)))
+		_, err = o.CommandRunner(c)
+		if err != nil {
+			return fmt.Errorf("failed to render the git operator chart: %w", err)
+		}
+	}
+
+	err = o.saveCAConfigMap(dir)
+	if err != nil {
+		return err
+	}
+
+	if o.SecretMode == SecretModeKube {
+		if o.GitURL == "" {
+			return options.MissingOption("url")
+		}
+		secret := operatorsecrets.NewSecret(o.Namespace, o.SecretName, o.gitCredentials())
+		fileName := filepath.Join(dir, o.SecretName+"-secret.yaml")
+		err = yamls.SaveFile(secret, fileName)
+		if err != nil {
+			return fmt.Errorf("failed to save Secret file %s: %w", fileName, err)
+		}
+		log.Logger().Warnf("the file %s contains the git credentials so make sure you do not check it into git", fileName)
+	}
+
+	log.Logger().Infof("rendered the git operator manifests to %s", termcolor.ColorInfo(dir))
+	return nil
+}
+
+// writeManifests splits the rendered manifest into files using the helm source comments
+func writeManifests(dir, manifest string) error {
+	contents := map[string]*strings.Builder{}
+	var fileNames []string
+	var buf *strings.Builder
+	for _, line := range strings.Split(manifest, "\n") {
+		if strings.HasPrefix(line, sourcePrefix) {
+			name := strings.TrimSpace(strings.TrimPrefix(line, sourcePrefix))
+			buf = contents[name]
+			if buf == nil {
+				buf = &strings.Builder{}
+				contents[name] = buf
+				fileNames = append(fileNames, name)
+			} else {
+				buf.WriteString("---\n")
+			}
+		}
+		if buf != nil {
+			buf.WriteString(line)
+			buf.WriteString("\n")
+		}
+	}
+
+	for _, name := range fileNames {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		err := os.MkdirAll(filepath.Dir(path), files.DefaultDirWritePermissions)
+		if err != nil {
+			return fmt.Errorf("failed to create dir for %s: %w", path, err)
+		}
+		err = os.WriteFile(path, []byte(contents[name].String()), files.DefaultFileWritePermissions)
+		if err != nil {
+			return fmt.Errorf("failed to save file %s: %w", path, err)
+		}
+	}
+	return nil
+}
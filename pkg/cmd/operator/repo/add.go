@@ -0,0 +1,263 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/bootjobs"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/gitcreds"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/netconfig"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/operatorsecrets"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/input"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/input/inputfactory"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/jxenv"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+)
+
+// AddOptions contains the command line arguments for the add command
+type AddOptions struct {
+	options.BaseOptions
+
+	Name                  string
+	Namespace             string
+	RepositoryNamespace   string
+	GitURL                string
+	GitKind               string
+	GitUserName           string
+	GitToken              string
+	Labels                []string
+	NoVerify              bool
+	SkipNamespaceCreation bool
+	Network               netconfig.Config
+	CommandRunner         cmdrunner.CommandRunner
+	HTTPClient            *http.Client
+	ScmClient             *scm.Client
+	KubeClient            kubernetes.Interface
+	Input                 input.Interface
+}
+
+var (
+	addLong = templates.LongDesc(`
+		Registers an additional git repository for the git operator to boot
+
+		Each repository has its own git operator Secret containing its URL and credentials along with any labels and the namespace its boot Jobs run in. This lets a shared platform cluster sync both the development environment and tenant repositories.
+
+		Use --ca-file, --http-proxy, --https-proxy and --no-proxy if the git server uses an internal CA or is behind a proxy when verifying the git credentials. The git operator clones the repository using the CA and proxies it was installed with.
+`)
+
+	addExample = templates.Examples(`
+* registers a tenant repository whose boot Jobs run in the tenant-a namespace
+` + bashExample("operator repo add tenant-a --url https://github.com/myorg/tenant-a-config.git --username mybotuser --token mytoken --repo-namespace tenant-a") + `
+* registers a repository with extra labels on its Secret
+` + bashExample("operator repo add tenant-b --url https://github.com/myorg/tenant-b-config.git --label team=b --label tier=tenant") + `
+`)
+)
+
+// NewCmdAdd creates the command to add a git repository
+func NewCmdAdd() (*cobra.Command, *AddOptions) {
+	o := &AddOptions{}
+	command := &cobra.Command{
+		Use:     "add <name>",
+		Short:   "registers an additional git repository for the git operator to boot",
+		Aliases: []string{"create"},
+		Long:    addLong,
+		Example: addExample,
+		Args:    cobra.ExactArgs(1),
+		Run: func(command *cobra.Command, args []string) {
+			o.Name = args[0]
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	command.Flags().StringVarP(&o.Namespace, "namespace", "n", "", "the namespace the git operator is installed in. If not specified it will look in: jx-git-operator and jx")
+	command.Flags().StringVarP(&o.RepositoryNamespace, "repo-namespace", "", "", "the namespace the boot Jobs of the repository run in. If not specified the git operator uses its default namespace")
+	command.Flags().StringVarP(&o.GitURL, "url", "u", "", "the git repository URL")
+	command.Flags().StringVarP(&o.GitKind, "git-kind", "", "", "the kind of git server. If not specified it is detected for the common SaaS git providers")
+	command.Flags().StringVarP(&o.GitUserName, "username", "", "", "the git username used to clone the git repository. Defaults to $GIT_USERNAME")
+	command.Flags().StringVarP(&o.GitToken, "token", "", "", "the git token used to clone the git repository. Defaults to $GIT_TOKEN")
+	command.Flags().StringArrayVarP(&o.Labels, "label", "l", nil, "the extra labels to add to the Secret of the repository in the form name=value")
+	command.Flags().BoolVarP(&o.NoVerify, "no-verify", "", false, "disables verifying the git credentials can read the git repository")
+	command.Flags().BoolVarP(&o.SkipNamespaceCreation, "skip-namespace-creation", "", false, "if enabled do not create the namespace of the repository if it does not exist")
+	o.Network.AddFlags(command)
+
+	o.BaseOptions.AddBaseFlags(command)
+
+	return command, o
+}
+
+// Run registers the git repository
+func (o *AddOptions) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return err
+	}
+	labels, err := parseLabels(o.Labels)
+	if err != nil {
+		return err
+	}
+
+	ns, err := bootjobs.FindGitOperatorNamespace(o.KubeClient, o.Namespace)
+	if err != nil {
+		return fmt.Errorf("failed to find the git operator namespace: %w", err)
+	}
+
+	ctx := context.TODO()
+	existing, err := o.KubeClient.CoreV1().Secrets(ns).Get(ctx, o.Name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to find Secret %s in namespace %s: %w", o.Name, ns, err)
+		}
+	} else {
+		current := operatorsecrets.GetCredentials(existing)
+		if current.URL != o.GitURL {
+			return fmt.Errorf("there is already a Secret %s in namespace %s for the git repository %s. Please use a different name or remove it first", o.Name, ns, current.URL)
+		}
+	}
+
+	if o.GitToken == "" {
+		if o.BatchMode {
+			return options.MissingOption("token")
+		}
+		o.GitToken, err = o.Input.PickPassword(fmt.Sprintf("Enter the Bot Git token the Kubernetes operator will use to clone the git repository %s", o.GitURL), "the token only requires read repository permissions")
+		if err != nil {
+			return fmt.Errorf("failed to get git token: %w", err)
+		}
+		if o.GitToken == "" {
+			return options.MissingOption("token")
+		}
+	}
+
+	if !o.NoVerify {
+		err = o.verifyCredentials(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	if o.RepositoryNamespace != "" && !o.SkipNamespaceCreation {
+		err = jxenv.EnsureNamespaceCreated(o.KubeClient, o.RepositoryNamespace, nil, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create namespace %s: %w", o.RepositoryNamespace, err)
+		}
+	}
+
+	secret := operatorsecrets.NewSecret(ns, o.Name, &operatorsecrets.Credentials{
+		URL:       o.GitURL,
+		Username:  o.GitUserName,
+		Password:  o.GitToken,
+		Namespace: o.RepositoryNamespace,
+	})
+	for k, v := range labels {
+		secret.Labels[k] = v
+	}
+	_, err = operatorsecrets.EnsureSecret(o.KubeClient, secret)
+	if err != nil {
+		return err
+	}
+	log.Logger().Infof("registered the git repository %s in Secret %s in namespace %s", info(o.GitURL), info(o.Name), info(ns))
+	return nil
+}
+
+// Validate verifies the settings are correct and we can lazy create any required resources
+func (o *AddOptions) Validate() error {
+	if o.Name == "" {
+		return options.MissingOption("name")
+	}
+	if msgs := validation.IsDNS1123Subdomain(o.Name); len(msgs) > 0 {
+		return options.InvalidOptionf("name", o.Name, "the name is used for the Secret of the repository: %s", strings.Join(msgs, ", "))
+	}
+	if o.RepositoryNamespace != "" {
+		if msgs := validation.IsDNS1123Label(o.RepositoryNamespace); len(msgs) > 0 {
+			return options.InvalidOptionf("repo-namespace", o.RepositoryNamespace, "%s", strings.Join(msgs, ", "))
+		}
+	}
+	if o.GitURL == "" {
+		return options.MissingOption("url")
+	}
+	err := gitcreds.RejectSSHURL("url", o.GitURL)
+	if err != nil {
+		return err
+	}
+	if o.GitToken == "" {
+		o.GitToken = os.Getenv("GIT_TOKEN")
+	}
+	if o.GitUserName == "" {
+		o.GitUserName = os.Getenv("GIT_USERNAME")
+	}
+	if o.GitUserName == "" {
+		return options.MissingOption("username")
+	}
+	if o.CommandRunner == nil {
+		o.CommandRunner = cmdrunner.QuietCommandRunner
+	}
+	if o.HTTPClient == nil {
+		o.HTTPClient, err = o.Network.HTTPClient()
+		if err != nil {
+			return fmt.Errorf("failed to configure the CA and proxies: %w", err)
+		}
+	}
+	o.CommandRunner, err = o.Network.CommandRunner(o.CommandRunner)
+	if err != nil {
+		return fmt.Errorf("failed to configure the CA and proxies for git: %w", err)
+	}
+	o.KubeClient, err = kube.LazyCreateKubeClientWithMandatory(o.KubeClient, true)
+	if err != nil {
+		return fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+	if o.Input == nil {
+		o.Input = inputfactory.NewInput(&o.BaseOptions)
+	}
+	return nil
+}
+
+// verifyCredentials verifies the git credentials can read the git repository so that we fail fast rather than
+// the boot Job failing to clone the repository inside the cluster
+func (o *AddOptions) verifyCredentials(ctx context.Context) error {
+	if o.ScmClient == nil {
+		var err error
+		o.ScmClient, _, err = gitcreds.NewScmClient(o.HTTPClient, o.GitURL, o.GitKind, o.GitUserName, o.GitToken)
+		if err != nil {
+			log.Logger().Debugf("could not create an SCM client so verifying the git credentials via git ls-remote: %s", err.Error())
+		}
+	}
+	return gitcreds.VerifyCredentials(ctx, o.ScmClient, o.CommandRunner, o.GitURL, o.GitUserName, o.GitToken)
+}
+
+// parseLabels parses the labels in the form name=value
+func parseLabels(values []string) (map[string]string, error) {
+	answer := map[string]string{}
+	for _, v := range values {
+		k, value, ok := strings.Cut(v, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, options.InvalidOptionf("label", v, "labels must be in the form name=value")
+		}
+		if k == bootjobs.LabelGitOperatorKind {
+			return nil, options.InvalidOptionf("label", v, "the label %s is reserved for the git operator", k)
+		}
+		if msgs := validation.IsQualifiedName(k); len(msgs) > 0 {
+			return nil, options.InvalidOptionf("label", v, "%s", strings.Join(msgs, ", "))
+		}
+		value = strings.TrimSpace(value)
+		if msgs := validation.IsValidLabelValue(value); len(msgs) > 0 {
+			return nil, options.InvalidOptionf("label", v, "%s", strings.Join(msgs, ", "))
+		}
+		answer[k] = value
+	}
+	return answer, nil
+}
@@ -0,0 +1,124 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/bootjobs"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/operatorsecrets"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/table"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ListOptions contains the command line arguments for the list command
+type ListOptions struct {
+	options.BaseOptions
+
+	Namespace  string
+	Out        io.Writer
+	KubeClient kubernetes.Interface
+	Secrets    []corev1.Secret
+}
+
+var (
+	listLong = templates.LongDesc(`
+		Lists the git repositories the git operator boots
+`)
+
+	listExample = templates.Examples(`
+* lists the git repositories the git operator boots
+` + bashExample("operator repo list") + `
+`)
+)
+
+// NewCmdList creates the command to list the git repositories
+func NewCmdList() (*cobra.Command, *ListOptions) {
+	o := &ListOptions{}
+	command := &cobra.Command{
+		Use:     "list",
+		Short:   "lists the git repositories the git operator boots",
+		Aliases: []string{"ls"},
+		Long:    listLong,
+		Example: listExample,
+		Run: func(command *cobra.Command, args []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	command.Flags().StringVarP(&o.Namespace, "namespace", "n", "", "the namespace the git operator is installed in. If not specified it will look in: jx-git-operator and jx")
+
+	o.BaseOptions.AddBaseFlags(command)
+
+	return command, o
+}
+
+// Run lists the git repositories
+func (o *ListOptions) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return err
+	}
+
+	ns, err := bootjobs.FindGitOperatorNamespace(o.KubeClient, o.Namespace)
+	if err != nil {
+		return fmt.Errorf("failed to find the git operator namespace: %w", err)
+	}
+
+	list, err := o.KubeClient.CoreV1().Secrets(ns).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: bootjobs.GitOperatorSecretSelector,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list the git operator Secrets in namespace %s with selector %s: %w", ns, bootjobs.GitOperatorSecretSelector, err)
+	}
+	o.Secrets = list.Items
+	sort.Slice(o.Secrets, func(i, j int) bool {
+		return o.Secrets[i].Name < o.Secrets[j].Name
+	})
+
+	t := table.CreateTable(o.Out)
+	t.AddRow("NAME", "URL", "USERNAME", "NAMESPACE", "LABELS")
+	for i := range o.Secrets {
+		secret := &o.Secrets[i]
+		creds := operatorsecrets.GetCredentials(secret)
+		t.AddRow(secret.Name, creds.URL, creds.Username, creds.Namespace, extraLabels(secret.Labels))
+	}
+	t.Render()
+	return nil
+}
+
+// Validate verifies the settings are correct and we can lazy create any required resources
+func (o *ListOptions) Validate() error {
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+	var err error
+	o.KubeClient, err = kube.LazyCreateKubeClientWithMandatory(o.KubeClient, true)
+	if err != nil {
+		return fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+	return nil
+}
+
+// extraLabels returns the labels of the Secret other than the git operator label
+func extraLabels(labels map[string]string) string {
+	var answer []string
+	for k, v := range labels {
+		if k != bootjobs.LabelGitOperatorKind {
+			answer = append(answer, k+"="+v)
+		}
+	}
+	sort.Strings(answer)
+	return strings.Join(answer, ",")
+}
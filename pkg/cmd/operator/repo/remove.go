@@ -0,0 +1,132 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/bootjobs"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/operatorsecrets"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/input"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/input/inputfactory"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// RemoveOptions contains the command line arguments for the remove command
+type RemoveOptions struct {
+	options.BaseOptions
+
+	Name       string
+	Namespace  string
+	KubeClient kubernetes.Interface
+	Input      input.Interface
+}
+
+var (
+	removeLong = templates.LongDesc(`
+		Removes a git repository so that the git operator no longer boots it
+
+		Deletes the git operator Secret of the repository. Any resources already applied from the repository are left in the cluster.
+`)
+
+	removeExample = templates.Examples(`
+* removes the tenant-a git repository
+` + bashExample("operator repo remove tenant-a") + `
+`)
+)
+
+// NewCmdRemove creates the command to remove a git repository
+func NewCmdRemove() (*cobra.Command, *RemoveOptions) {
+	o := &RemoveOptions{}
+	command := &cobra.Command{
+		Use:     "remove <name>",
+		Short:   "removes a git repository so that the git operator no longer boots it",
+		Aliases: []string{"rm", "delete"},
+		Long:    removeLong,
+		Example: removeExample,
+		Args:    cobra.ExactArgs(1),
+		Run: func(command *cobra.Command, args []string) {
+			o.Name = args[0]
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	command.Flags().StringVarP(&o.Namespace, "namespace", "n", "", "the namespace the git operator is installed in. If not specified it will look in: jx-git-operator and jx")
+
+	o.BaseOptions.AddBaseFlags(command)
+
+	return command, o
+}
+
+// Run removes the git repository
+func (o *RemoveOptions) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return err
+	}
+
+	ns, err := bootjobs.FindGitOperatorNamespace(o.KubeClient, o.Namespace)
+	if err != nil {
+		return fmt.Errorf("failed to find the git operator namespace: %w", err)
+	}
+
+	ctx := context.TODO()
+	secretInterface := o.KubeClient.CoreV1().Secrets(ns)
+	secret, err := secretInterface.Get(ctx, o.Name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("there is no git repository %s in namespace %s", o.Name, ns)
+		}
+		return fmt.Errorf("failed to find Secret %s in namespace %s: %w", o.Name, ns, err)
+	}
+	if secret.Labels[bootjobs.LabelGitOperatorKind] != bootjobs.GitOperatorKind {
+		return fmt.Errorf("the Secret %s in namespace %s is not a git operator Secret as it does not have the label %s", o.Name, ns, bootjobs.GitOperatorSecretSelector)
+	}
+	creds := operatorsecrets.GetCredentials(secret)
+
+	if !o.BatchMode {
+		message := fmt.Sprintf("Are you sure you want to remove the git repository %s?", creds.URL)
+		if o.Name == operatorsecrets.DefaultSecretName {
+			message = fmt.Sprintf("The Secret %s is usually the development environment. Are you sure you want to remove the git repository %s?", o.Name, creds.URL)
+		}
+		flag, err := o.Input.Confirm(message, false, "the git operator will no longer boot the repository")
+		if err != nil {
+			return fmt.Errorf("failed to confirm: %w", err)
+		}
+		if !flag {
+			log.Logger().Infof("not removing the git repository %s", info(o.Name))
+			return nil
+		}
+	}
+
+	err = secretInterface.Delete(ctx, o.Name, metav1.DeleteOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to delete Secret %s in namespace %s: %w", o.Name, ns, err)
+	}
+	log.Logger().Infof("removed the git repository %s by deleting Secret %s in namespace %s", info(creds.URL), info(o.Name), info(ns))
+	return nil
+}
+
+// Validate verifies the settings are correct and we can lazy create any required resources
+func (o *RemoveOptions) Validate() error {
+	if o.Name == "" {
+		return options.MissingOption("name")
+	}
+	var err error
+	o.KubeClient, err = kube.LazyCreateKubeClientWithMandatory(o.KubeClient, true)
+	if err != nil {
+		return fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+	if o.Input == nil {
+		o.Input = inputfactory.NewInput(&o.BaseOptions)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package repo
+
+import (
+	"fmt"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/common"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+var info = termcolor.ColorInfo
+
+// bashExample returns markdown for a bash script expression
+func bashExample(cli string) string {
+	return fmt.Sprintf("\n```bash \n%s %s\n```\n", common.BinaryName, cli)
+}
+
+// NewCmdRepo creates the command for working with the git repositories the git operator boots
+func NewCmdRepo() *cobra.Command {
+	command := &cobra.Command{
+		Use:     "repo",
+		Aliases: []string{"repos", "repository", "repositories"},
+		Short:   "Commands for working with the git repositories the git operator boots",
+		Run: func(command *cobra.Command, args []string) {
+			err := command.Help()
+			if err != nil {
+				log.Logger().Error(err.Error())
+			}
+		},
+	}
+	command.AddCommand(cobras.SplitCommand(NewCmdAdd()))
+	command.AddCommand(cobras.SplitCommand(NewCmdList()))
+	command.AddCommand(cobras.SplitCommand(NewCmdRemove()))
+	return command
+}
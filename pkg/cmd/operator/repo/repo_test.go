@@ -0,0 +1,156 @@
+package repo_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/bootjobs"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/cmd/operator/repo"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/operatorsecrets"
+	"github.com/jenkins-x/go-scm/scm"
+	fakescm "github.com/jenkins-x/go-scm/scm/driver/fake"
+	fakeinput "github.com/jenkins-x/jx-helpers/v3/pkg/input/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const ns = "jx-git-operator"
+
+func TestRepoAddListRemove(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      bootjobs.GitOperatorDeploymentName,
+				Namespace: ns,
+			},
+		},
+		operatorsecrets.NewSecret(ns, operatorsecrets.DefaultSecretName, &operatorsecrets.Credentials{
+			URL:      "https://github.com/myorg/environment-mycluster-dev.git",
+			Username: "fakeuser",
+			Password: "faketoken",
+		}),
+	)
+	scmClient, fakeData := fakescm.NewDefault()
+	fakeData.Repositories = append(fakeData.Repositories, &scm.Repository{
+		Namespace: "myorg",
+		Name:      "tenant-a-config",
+		FullName:  "myorg/tenant-a-config",
+	})
+
+	_, ao := repo.NewCmdAdd()
+	ao.Name = "tenant-a"
+	ao.GitURL = "https://github.com/myorg/tenant-a-config.git"
+	ao.GitUserName = "tenantuser"
+	ao.GitToken = "tenanttoken"
+	ao.RepositoryNamespace = "tenant-a"
+	ao.Labels = []string{"team=a"}
+	ao.ScmClient = scmClient
+	ao.KubeClient = kubeClient
+	ao.BatchMode = true
+
+	err := ao.Run()
+	require.NoError(t, err, "failed to add the repository")
+
+	ctx := context.TODO()
+	secret, err := kubeClient.CoreV1().Secrets(ns).Get(ctx, "tenant-a", metav1.GetOptions{})
+	require.NoError(t, err, "failed to find the Secret of the repository")
+	assert.Equal(t, bootjobs.GitOperatorKind, secret.Labels[bootjobs.LabelGitOperatorKind], "git operator label")
+	assert.Equal(t, "a", secret.Labels["team"], "extra label")
+
+	creds := operatorsecrets.GetCredentials(secret)
+	assert.Equal(t, ao.GitURL, creds.URL, "url")
+	assert.Equal(t, "tenantuser", creds.Username, "username")
+	assert.Equal(t, "tenanttoken", creds.Password, "password")
+	assert.Equal(t, "tenant-a", creds.Namespace, "namespace")
+
+	_, err = kubeClient.CoreV1().Namespaces().Get(ctx, "tenant-a", metav1.GetOptions{})
+	require.NoError(t, err, "should have created the namespace of the repository")
+
+	_, lo := repo.NewCmdList()
+	out := &bytes.Buffer{}
+	lo.Out = out
+	lo.KubeClient = kubeClient
+
+	err = lo.Run()
+	require.NoError(t, err, "failed to list the repositories")
+	require.Len(t, lo.Secrets, 2, "repositories")
+	assert.Equal(t, operatorsecrets.DefaultSecretName, lo.Secrets[0].Name, "first repository")
+	assert.Equal(t, "tenant-a", lo.Secrets[1].Name, "second repository")
+	assert.Contains(t, out.String(), "https://github.com/myorg/tenant-a-config.git", "output")
+	assert.NotContains(t, out.String(), "tenanttoken", "output should not contain the token")
+	t.Logf("list output:\n%s\n", out.String())
+
+	_, ro := repo.NewCmdRemove()
+	ro.Name = "tenant-a"
+	ro.KubeClient = kubeClient
+	ro.Input = &fakeinput.FakeInput{OrderedValues: []string{"yes"}}
+
+	err = ro.Run()
+	require.NoError(t, err, "failed to remove the repository")
+
+	_, err = kubeClient.CoreV1().Secrets(ns).Get(ctx, "tenant-a", metav1.GetOptions{})
+	require.Error(t, err, "should have deleted the Secret of the repository")
+	assert.True(t, apierrors.IsNotFound(err), "should be a not found error")
+}
+
+func TestRepoAddInvalid(t *testing.T) {
+	testCases := []struct {
+		name   string
+		gitURL string
+		labels []string
+	}{
+		{
+			name:   "Invalid_Name",
+			gitURL: "https://github.com/myorg/tenant-a-config.git",
+		},
+		{
+			name:   "tenant-a",
+			gitURL: "https://github.com/myorg/tenant-a-config.git",
+			labels: []string{"team"},
+		},
+		{
+			name:   "tenant-a",
+			gitURL: "https://github.com/myorg/tenant-a-config.git",
+			labels: []string{bootjobs.LabelGitOperatorKind + "=something"},
+		},
+		{
+			name:   operatorsecrets.DefaultSecretName,
+			gitURL: "https://github.com/myorg/tenant-a-config.git",
+		},
+	}
+
+	for _, tc := range testCases {
+		kubeClient := fake.NewSimpleClientset(
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      bootjobs.GitOperatorDeploymentName,
+					Namespace: ns,
+				},
+			},
+			operatorsecrets.NewSecret(ns, operatorsecrets.DefaultSecretName, &operatorsecrets.Credentials{
+				URL:      "https://github.com/myorg/environment-mycluster-dev.git",
+				Username: "fakeuser",
+				Password: "faketoken",
+			}),
+		)
+
+		_, ao := repo.NewCmdAdd()
+		ao.Name = tc.name
+		ao.GitURL = tc.gitURL
+		ao.GitUserName = "tenantuser"
+		ao.GitToken = "tenanttoken"
+		ao.Labels = tc.labels
+		ao.NoVerify = true
+		ao.KubeClient = kubeClient
+		ao.BatchMode = true
+
+		err := ao.Run()
+		require.Error(t, err, "should have failed to add repository %s with labels %v", tc.name, tc.labels)
+		t.Logf("got expected error for %s: %s\n", tc.name, err.Error())
+	}
+}
@@ -0,0 +1,329 @@
+package rollback
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/bootjobs"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/common"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/plugins/helmplugin"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/input"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/input/inputfactory"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/table"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+
+	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Options contains the command line arguments for this command
+type Options struct {
+	options.BaseOptions
+
+	Namespace     string
+	ReleaseName   string
+	HelmBin       string
+	Revision      int
+	Duration      time.Duration
+	PollPeriod    time.Duration
+	NoWait        bool
+	DryRun        bool
+	Out           io.Writer
+	History       []*Revision
+	CommandRunner cmdrunner.CommandRunner
+	KubeClient    kubernetes.Interface
+	Input         input.Interface
+}
+
+// Revision a revision in the history of the helm release as output by 'helm history --output json'
+type Revision struct {
+	Revision    int       `json:"revision"`
+	Updated     time.Time `json:"updated"`
+	Status      string    `json:"status"`
+	Chart       string    `json:"chart"`
+	AppVersion  string    `json:"app_version"`
+	Description string    `json:"description"`
+}
+
+var (
+	info = termcolor.ColorInfo
+
+	cmdLong = templates.LongDesc(`
+		Rolls back the git operator to a previous revision of its helm release
+
+		Lists the history of the git operator helm release and rolls back to the given revision or, if no revision is given, the selected revision. Then waits for the rollout of the git operator Deployment to complete
+`)
+
+	cmdExample = templates.Examples(`
+* lists the history of the git operator release and picks the revision to roll back to
+` + bashExample("operator rollback") + `
+* rolls back the git operator to revision 3
+` + bashExample("operator rollback 3") + `
+* rolls back the git operator to the previous revision without prompting
+` + bashExample("operator rollback --batch-mode") + `
+`)
+)
+
+// bashExample returns markdown for a bash script expression
+func bashExample(cli string) string {
+	return fmt.Sprintf("\n```bash \n%s %s\n```\n", common.BinaryName, cli)
+}
+
+// NewCmdRollback creates the new command
+func NewCmdRollback() (*cobra.Command, *Options) {
+	o := &Options{}
+	command := &cobra.Command{
+		Use:     "rollback [revision]",
+		Short:   "rolls back the git operator to a previous revision of its helm release",
+		Aliases: []string{"undo"},
+		Long:    cmdLong,
+		Example: cmdExample,
+		Args:    cobra.MaximumNArgs(1),
+		Run: func(command *cobra.Command, args []string) {
+			if len(args) > 0 {
+				revision, err := strconv.Atoi(args[0])
+				if err != nil {
+					helper.CheckErr(options.InvalidOptionf("revision", args[0], "the revision must be a number"))
+				}
+				o.Revision = revision
+			}
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	command.Flags().StringVarP(&o.Namespace, "namespace", "n", "", "the namespace the git operator is installed in. If not specified it will look in: jx-git-operator and jx")
+	command.Flags().StringVarP(&o.ReleaseName, "name", "", common.DefaultOperatorReleaseName, "the helm release name of the git operator")
+	command.Flags().DurationVarP(&o.Duration, "duration", "", time.Minute*10, "how long to wait for the rollout of the git operator Deployment after rolling back")
+	command.Flags().DurationVarP(&o.PollPeriod, "poll", "", time.Second*2, "duration between polls of the git operator Deployment while waiting for the rollout")
+	command.Flags().BoolVarP(&o.NoWait, "no-wait", "", false, "disables waiting for the rollout of the git operator Deployment after rolling back")
+	command.Flags().BoolVarP(&o.DryRun, "dry-run", "", false, "if enabled just display the helm command that would roll back the git operator but don't actually do anything")
+
+	o.BaseOptions.AddBaseFlags(command)
+
+	return command, o
+}
+
+// Run rolls back the git operator
+func (o *Options) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return err
+	}
+
+	o.Namespace, err = bootjobs.FindGitOperatorNamespace(o.KubeClient, o.Namespace)
+	if err != nil {
+		return fmt.Errorf("failed to find the git operator namespace: %w", err)
+	}
+
+	o.History, err = o.getHistory()
+	if err != nil {
+		return err
+	}
+	if len(o.History) < 2 {
+		return fmt.Errorf("there are no previous revisions of release %s in namespace %s to roll back to", o.ReleaseName, o.Namespace)
+	}
+	o.renderHistory()
+
+	if o.Revision == 0 {
+		o.Revision, err = o.pickRevision()
+		if err != nil {
+			return err
+		}
+	}
+	if o.findRevision(o.Revision) == nil {
+		return options.InvalidOptionf("revision", strconv.Itoa(o.Revision), "there is no revision %d of release %s in namespace %s", o.Revision, o.ReleaseName, o.Namespace)
+	}
+
+	c := &cmdrunner.Command{
+		Name: o.HelmBin,
+		Args: []string{"rollback", o.ReleaseName, strconv.Itoa(o.Revision), "--namespace", o.Namespace},
+	}
+	commandLine := cmdrunner.CLI(c)
+	if o.DryRun {
+		log.Logger().Infof("\nTo roll back the git operator run this command:\n\n%s\n\n", info(commandLine))
+		return nil
+	}
+
+	log.Logger().Infof("running command:\n\n%s\n\n", info(commandLine))
+	_, err = o.CommandRunner(c)
+	if err != nil {
+		return fmt.Errorf("failed to run command %s: %w", commandLine, err)
+	}
+	log.Logger().Infof("rolled back release %s in namespace %s to revision %s", info(o.ReleaseName), info(o.Namespace), info(o.Revision))
+
+	if o.NoWait {
+		return nil
+	}
+	name := bootjobs.GitOperatorDeploymentName
+	log.Logger().Infof("waiting for the rollout of Deployment %s in namespace %s...", info(name), info(o.Namespace))
+	err = o.waitForRollout(name)
+	if err != nil {
+		return fmt.Errorf("failed waiting for the rollout of Deployment %s in namespace %s: %w", name, o.Namespace, err)
+	}
+	log.Logger().Infof("the git operator Deployment %s has rolled out", info(name))
+	return nil
+}
+
+// waitForRollout waits for the Deployment to have observed the rollback and for all of its replicas to be updated and ready
+func (o *Options) waitForRollout(name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), o.Duration)
+	defer cancel()
+
+	status := ""
+	err := wait.PollUntilContextCancel(ctx, o.PollPeriod, true, func(ctx context.Context) (bool, error) {
+		deployment, err := o.KubeClient.AppsV1().Deployments(o.Namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, fmt.Errorf("failed to get Deployment %s: %w", name, err)
+		}
+		done, s := rolloutStatus(deployment)
+		if s != status {
+			log.Logger().Infof("%s", s)
+			status = s
+		}
+		return done, nil
+	})
+	if wait.Interrupted(err) {
+		return fmt.Errorf("timed out after waiting for duration %s: %s", o.Duration.String(), status)
+	}
+	return err
+}
+
+// rolloutStatus returns true if the rollout of the Deployment has completed along with a description of its status
+func rolloutStatus(deployment *appsv1.Deployment) (bool, string) {
+	if deployment.Generation > deployment.Status.ObservedGeneration {
+		return false, "waiting for the Deployment spec update to be observed"
+	}
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	s := &deployment.Status
+	switch {
+	case s.UpdatedReplicas < replicas:
+		return false, fmt.Sprintf("waiting for the rollout: %d of %d updated replicas are available", s.UpdatedReplicas, replicas)
+	case s.Replicas > s.UpdatedReplicas:
+		return false, fmt.Sprintf("waiting for the rollout: %d old replicas are pending termination", s.Replicas-s.UpdatedReplicas)
+	case s.ReadyReplicas < replicas:
+		return false, fmt.Sprintf("waiting for the rollout: %d of %d updated replicas are ready", s.ReadyReplicas, replicas)
+	}
+	return true, "the rollout has completed"
+}
+
+// Validate verifies the settings are correct and we can lazy create any required resources
+func (o *Options) Validate() error {
+	if o.ReleaseName == "" {
+		return options.MissingOption("name")
+	}
+	if o.Revision < 0 {
+		return options.InvalidOptionf("revision", strconv.Itoa(o.Revision), "the revision must be positive")
+	}
+	if o.Duration == 0 {
+		o.Duration = time.Minute * 10
+	}
+	if o.PollPeriod == 0 {
+		o.PollPeriod = time.Second * 2
+	}
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+	if o.CommandRunner == nil {
+		o.CommandRunner = cmdrunner.QuietCommandRunner
+	}
+	var err error
+	if o.HelmBin == "" {
+		o.HelmBin, err = helmplugin.GetHelm3Binary()
+		if err != nil {
+			return err
+		}
+	}
+	o.KubeClient, err = kube.LazyCreateKubeClientWithMandatory(o.KubeClient, true)
+	if err != nil {
+		return fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+	if o.Input == nil {
+		o.Input = inputfactory.NewInput(&o.BaseOptions)
+	}
+	return nil
+}
+
+// getHistory returns the revisions of the release with the latest revision last
+func (o *Options) getHistory() ([]*Revision, error) {
+	c := &cmdrunner.Command{
+		Name: o.HelmBin,
+		Args: []string{"history", o.ReleaseName, "--namespace", o.Namespace, "--output", "json"},
+	}
+	text, err := o.CommandRunner(c)
+	if err != nil {
+		if strings.Contains(text, "not found") || strings.Contains(err.Error(), "not found") {
+			return nil, fmt.Errorf("there is no helm release %s in namespace %s", o.ReleaseName, o.Namespace)
+		}
+		return nil, fmt.Errorf("failed to run command %s: %w", cmdrunner.CLI(c), err)
+	}
+	var answer []*Revision
+	err = json.Unmarshal([]byte(text), &answer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the history of release %s: %w", o.ReleaseName, err)
+	}
+	return answer, nil
+}
+
+func (o *Options) renderHistory() {
+	t := table.CreateTable(o.Out)
+	t.AddRow("REVISION", "UPDATED", "STATUS", "CHART", "APP VERSION", "DESCRIPTION")
+	for _, r := range o.History {
+		t.AddRow(strconv.Itoa(r.Revision), r.Updated.Format(time.RFC1123), r.Status, r.Chart, r.AppVersion, r.Description)
+	}
+	t.Render()
+}
+
+// pickRevision picks the revision to roll back to defaulting to the previous revision
+func (o *Options) pickRevision() (int, error) {
+	current := o.History[len(o.History)-1]
+	previous := o.History[len(o.History)-2]
+	if o.BatchMode {
+		return previous.Revision, nil
+	}
+
+	var names []string
+	for i := len(o.History) - 2; i >= 0; i-- {
+		r := o.History[i]
+		names = append(names, revisionName(r))
+	}
+	name, err := o.Input.PickNameWithDefault(names, fmt.Sprintf("Pick the revision of release %s to roll back to from revision %d", o.ReleaseName, current.Revision), revisionName(previous), "the git operator chart is rolled back to the chart version and values of the revision")
+	if err != nil {
+		return 0, fmt.Errorf("failed to pick the revision: %w", err)
+	}
+	revision, err := strconv.Atoi(strings.Fields(name)[0])
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse the revision %s: %w", name, err)
+	}
+	return revision, nil
+}
+
+func (o *Options) findRevision(revision int) *Revision {
+	for _, r := range o.History {
+		if r.Revision == revision {
+			return r
+		}
+	}
+	return nil
+}
+
+func revisionName(r *Revision) string {
+	return fmt.Sprintf("%d %s %s %s", r.Revision, r.Chart, r.Status, r.Updated.Format(time.RFC3339))
+}
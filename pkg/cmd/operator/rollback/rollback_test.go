@@ -0,0 +1,124 @@
+package rollback_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/bootjobs"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/cmd/operator/rollback"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner/fakerunner"
+	fakeinput "github.com/jenkins-x/jx-helpers/v3/pkg/input/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const history = `[
+  {"revision": 1, "updated": "2024-01-02T10:00:00Z", "status": "superseded", "chart": "jx-git-operator-0.1.0", "app_version": "0.1.0", "description": "Install complete"},
+  {"revision": 2, "updated": "2024-01-03T10:00:00Z", "status": "superseded", "chart": "jx-git-operator-0.1.1", "app_version": "0.1.1", "description": "Upgrade complete"},
+  {"revision": 3, "updated": "2024-01-04T10:00:00Z", "status": "deployed", "chart": "jx-git-operator-0.1.2", "app_version": "0.1.2", "description": "Upgrade complete"}
+]`
+
+func TestRollback(t *testing.T) {
+	ns := "jx-git-operator"
+
+	testCases := []struct {
+		name           string
+		revision       int
+		batchMode      bool
+		answer         string
+		expectRevision string
+		notRolledOut   bool
+		expectError    bool
+	}{
+		{
+			name:           "explicit-revision",
+			revision:       1,
+			expectRevision: "1",
+		},
+		{
+			name:           "batch-mode-previous",
+			batchMode:      true,
+			expectRevision: "2",
+		},
+		{
+			name:           "pick-revision",
+			answer:         "1 jx-git-operator-0.1.0 superseded 2024-01-02T10:00:00Z",
+			expectRevision: "1",
+		},
+		{
+			name:        "missing-revision",
+			revision:    7,
+			expectError: true,
+		},
+		{
+			name:         "not-rolled-out",
+			revision:     1,
+			notRolledOut: true,
+			expectError:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       bootjobs.GitOperatorDeploymentName,
+				Namespace:  ns,
+				Generation: 4,
+			},
+			Status: appsv1.DeploymentStatus{
+				ObservedGeneration: 4,
+				Replicas:           1,
+				UpdatedReplicas:    1,
+				ReadyReplicas:      1,
+			},
+		}
+		if tc.notRolledOut {
+			// the old replica is still running
+			deployment.Status.Replicas = 2
+		}
+		kubeClient := fake.NewSimpleClientset(deployment)
+
+		runner := &fakerunner.FakeRunner{
+			CommandRunner: func(c *cmdrunner.Command) (string, error) {
+				if len(c.Args) > 0 && c.Args[0] == "history" {
+					return history, nil
+				}
+				return "", nil
+			},
+		}
+
+		_, o := rollback.NewCmdRollback()
+		o.CommandRunner = runner.Run
+		o.HelmBin = "helm"
+		o.KubeClient = kubeClient
+		o.Revision = tc.revision
+		o.BatchMode = tc.batchMode
+		o.Duration = time.Millisecond * 100
+		o.PollPeriod = time.Millisecond * 10
+		out := &bytes.Buffer{}
+		o.Out = out
+		o.Input = &fakeinput.FakeInput{OrderedValues: []string{tc.answer}}
+
+		err := o.Run()
+		if tc.expectError {
+			require.Error(t, err, "expected error for %s", tc.name)
+			if tc.notRolledOut {
+				assert.Contains(t, err.Error(), "old replicas are pending termination", "error for %s", tc.name)
+			}
+			continue
+		}
+		require.NoError(t, err, "failed to run for %s", tc.name)
+
+		runner.ExpectResults(t,
+			fakerunner.FakeResult{CLI: "helm history jxgo --namespace jx-git-operator --output json"},
+			fakerunner.FakeResult{CLI: "helm rollback jxgo " + tc.expectRevision + " --namespace jx-git-operator"},
+		)
+		assert.Len(t, o.History, 3, "history for %s", tc.name)
+		assert.Contains(t, out.String(), "jx-git-operator-0.1.1", "history table for %s", tc.name)
+	}
+}
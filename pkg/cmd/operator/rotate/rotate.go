@@ -0,0 +1,302 @@
+package rotate
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/bootjobs"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/cmd/joblog"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/cmd/trigger"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/common"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/gitcreds"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/netconfig"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/operatorsecrets"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/input"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/input/inputfactory"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// AnnotationRestartedAt the annotation used to restart the pods of a Deployment in the same way as 'kubectl rollout restart'
+	AnnotationRestartedAt = "kubectl.kubernetes.io/restartedAt"
+)
+
+// Options contains the command line arguments for this command
+type Options struct {
+	options.BaseOptions
+
+	Namespace   string
+	SecretName  string
+	GitURL      string
+	GitKind     string
+	GitUserName string
+	GitToken    string
+	JobSelector string
+
+	GitHubAppID             string
+	GitHubAppInstallationID string
+	GitHubAppPrivateKeyFile string
+
+	NoVerify      bool
+	NoRestart     bool
+	Trigger       bool
+	NoLog         bool
+	Network       netconfig.Config
+	JobLogOptions joblog.Options
+	CommandRunner cmdrunner.CommandRunner
+	HTTPClient    *http.Client
+	ScmClient     *scm.Client
+	KubeClient    kubernetes.Interface
+	Input         input.Interface
+}
+
+var (
+	info = termcolor.ColorInfo
+
+	cmdLong = templates.LongDesc(`
+		Rotates the git credentials the git operator uses to clone the git repository
+
+		Verifies the new username and token can read the git repository, updates the git operator Secret and restarts the git operator without reinstalling it
+`)
+
+	cmdExample = templates.Examples(`
+* rotates the git token of the git operator
+` + bashExample("operator rotate-credentials --username mybotuser --token mynewtoken") + `
+* rotates the git token of the git operator for a git server using an internal CA behind a proxy
+` + bashExample("operator rotate-credentials --username mybotuser --token mynewtoken --ca-file my-ca.crt --https-proxy http://proxy.example.com:3128") + `
+* rotates the git token then triggers the boot Job to verify the new token can clone the git repository
+` + bashExample("operator rotate-credentials --username mybotuser --token mynewtoken --trigger") + `
+`)
+)
+
+// bashExample returns markdown for a bash script expression
+func bashExample(cli string) string {
+	return fmt.Sprintf("\n```bash \n%s %s\n```\n", common.BinaryName, cli)
+}
+
+// NewCmdRotateCredentials creates the new command
+func NewCmdRotateCredentials() (*cobra.Command, *Options) {
+	o := &Options{}
+	command := &cobra.Command{
+		Use:     "rotate-credentials",
+		Short:   "rotates the git credentials of the git operator without reinstalling it",
+		Aliases: []string{"rotate"},
+		Long:    cmdLong,
+		Example: cmdExample,
+		Run: func(command *cobra.Command, args []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	command.Flags().StringVarP(&o.Namespace, "namespace", "n", "", "the namespace the git operator is installed in. If not specified it will look in: jx-git-operator and jx")
+	command.Flags().StringVarP(&o.SecretName, "secret-name", "", operatorsecrets.DefaultSecretName, "the name of the git operator Secret containing the git credentials")
+	command.Flags().StringVarP(&o.GitURL, "url", "u", "", "the git repository URL. If not specified it is read from the git operator Secret")
+	command.Flags().StringVarP(&o.GitKind, "git-kind", "", "", "the kind of git server. If not specified it is detected for the common SaaS git providers")
+	command.Flags().StringVarP(&o.GitUserName, "username", "", "", "the new git username used to clone the git repository. Defaults to $GIT_USERNAME or the current username in the Secret")
+	command.Flags().StringVarP(&o.GitToken, "token", "", "", "the new git token used to clone the git repository. Defaults to $GIT_TOKEN")
+	command.Flags().StringVarP(&o.GitHubAppID, "github-app-id", "", "", "the ID of the GitHub App to authenticate the git operator with. Not supported yet as the git operator cannot mint its own installation tokens")
+	command.Flags().StringVarP(&o.GitHubAppInstallationID, "github-app-installation-id", "", "", "the installation ID of the GitHub App in the organisation of the git repository. Not supported yet")
+	command.Flags().StringVarP(&o.GitHubAppPrivateKeyFile, "github-app-private-key", "", "", "the file containing the PEM encoded private key of the GitHub App. Not supported yet")
+	command.Flags().StringVarP(&o.JobSelector, "selector", "s", bootjobs.DefaultJobSelector, "the selector of the boot Jobs to trigger if --trigger is enabled")
+	command.Flags().BoolVarP(&o.NoVerify, "no-verify", "", false, "disables verifying the new credentials against the git server")
+	command.Flags().BoolVarP(&o.NoRestart, "no-restart", "", false, "disables restarting the git operator after updating the Secret")
+	command.Flags().BoolVarP(&o.Trigger, "trigger", "", false, "triggers the latest boot Job to run again to verify the new credentials can clone the git repository")
+	command.Flags().BoolVarP(&o.NoLog, "no-log", "", false, "to disable viewing the logs of the triggered boot Job")
+
+	o.Network.AddFlags(command)
+
+	_, jo := joblog.NewCmdJobLog()
+	o.JobLogOptions = *jo
+	command.Flags().DurationVarP(&o.JobLogOptions.Duration, "max-log-duration", "", time.Minute*30, "how long to wait for a boot Job pod to be ready to view its log")
+
+	o.BaseOptions.AddBaseFlags(command)
+
+	return command, o
+}
+
+// Run rotates the git credentials
+func (o *Options) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return err
+	}
+
+	ns, err := bootjobs.FindGitOperatorNamespace(o.KubeClient, o.Namespace)
+	if err != nil {
+		return fmt.Errorf("failed to find the git operator namespace: %w", err)
+	}
+
+	ctx := context.TODO()
+	secretInterface := o.KubeClient.CoreV1().Secrets(ns)
+	secret, err := secretInterface.Get(ctx, o.SecretName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("there is no git operator Secret %s in namespace %s. Are you sure the git operator is installed?", o.SecretName, ns)
+		}
+		return fmt.Errorf("failed to find Secret %s in namespace %s: %w", o.SecretName, ns, err)
+	}
+
+	creds := operatorsecrets.GetCredentials(secret)
+	if o.GitURL == "" {
+		o.GitURL = creds.URL
+	}
+	if o.GitUserName == "" {
+		o.GitUserName = creds.Username
+	}
+	if o.GitUserName == "" {
+		return options.MissingOption("username")
+	}
+	if o.GitToken == "" {
+		if o.BatchMode {
+			return options.MissingOption("token")
+		}
+		o.GitToken, err = o.Input.PickPassword("Enter the new Bot Git token the Kubernetes operator will use to clone the environment git repository", "the token only requires read repository permissions")
+		if err != nil {
+			return fmt.Errorf("failed to get git token: %w", err)
+		}
+		if o.GitToken == "" {
+			return options.MissingOption("token")
+		}
+	}
+
+	if !o.NoVerify {
+		err = o.verifyCredentials(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[operatorsecrets.KeyUsername] = []byte(o.GitUserName)
+	secret.Data[operatorsecrets.KeyPassword] = []byte(o.GitToken)
+	_, err = secretInterface.Update(ctx, secret, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update Secret %s in namespace %s: %w", o.SecretName, ns, err)
+	}
+	log.Logger().Infof("updated the git credentials in Secret %s in namespace %s", info(o.SecretName), info(ns))
+
+	if !o.NoRestart {
+		err = o.restartGitOperator(ctx, ns)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !o.Trigger {
+		return nil
+	}
+	to := &trigger.Options{
+		Namespace:   ns,
+		JobSelector: o.JobSelector,
+		KubeClient:  o.KubeClient,
+	}
+	err = to.Run()
+	if err != nil {
+		return fmt.Errorf("failed to trigger the boot Job: %w", err)
+	}
+	if o.NoLog {
+		return nil
+	}
+	o.JobLogOptions.Namespace = ns
+	o.JobLogOptions.KubeClient = o.KubeClient
+	o.JobLogOptions.WaitMode = true
+	err = o.JobLogOptions.Run()
+	if err != nil {
+		return fmt.Errorf("failed to tail the boot Job pods: %w", err)
+	}
+	return nil
+}
+
+// Validate verifies the settings are correct and we can lazy create any required resources
+func (o *Options) Validate() error {
+	err := gitcreds.RejectGitHubApp(o.GitHubAppID, o.GitHubAppInstallationID, o.GitHubAppPrivateKeyFile)
+	if err != nil {
+		return err
+	}
+	if o.SecretName == "" {
+		o.SecretName = operatorsecrets.DefaultSecretName
+	}
+	if o.JobSelector == "" {
+		o.JobSelector = bootjobs.DefaultJobSelector
+	}
+	if o.GitToken == "" {
+		o.GitToken = os.Getenv("GIT_TOKEN")
+	}
+	if o.GitUserName == "" {
+		o.GitUserName = os.Getenv("GIT_USERNAME")
+	}
+	o.KubeClient, err = kube.LazyCreateKubeClientWithMandatory(o.KubeClient, true)
+	if err != nil {
+		return fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+	if o.Input == nil {
+		o.Input = inputfactory.NewInput(&o.BaseOptions)
+	}
+	if o.CommandRunner == nil {
+		o.CommandRunner = cmdrunner.QuietCommandRunner
+	}
+	if o.HTTPClient == nil {
+		o.HTTPClient, err = o.Network.HTTPClient()
+		if err != nil {
+			return fmt.Errorf("failed to configure the CA and proxies: %w", err)
+		}
+	}
+	o.CommandRunner, err = o.Network.CommandRunner(o.CommandRunner)
+	if err != nil {
+		return fmt.Errorf("failed to configure the CA and proxies for git: %w", err)
+	}
+	return nil
+}
+
+// verifyCredentials verifies the new git credentials can read the git repository before we update the Secret so
+// that we do not break the git operator. Falls back to 'git ls-remote' if we cannot create an SCM client
+func (o *Options) verifyCredentials(ctx context.Context) error {
+	if o.GitURL == "" {
+		return options.MissingOption("url")
+	}
+	if o.ScmClient == nil {
+		var err error
+		o.ScmClient, _, err = gitcreds.NewScmClient(o.HTTPClient, o.GitURL, o.GitKind, o.GitUserName, o.GitToken)
+		if err != nil {
+			log.Logger().Debugf("could not create an SCM client so verifying the git credentials via git ls-remote: %s", err.Error())
+		}
+	}
+	return gitcreds.VerifyCredentials(ctx, o.ScmClient, o.CommandRunner, o.GitURL, o.GitUserName, o.GitToken)
+}
+
+// restartGitOperator restarts the git operator pods in the same way as 'kubectl rollout restart'
+func (o *Options) restartGitOperator(ctx context.Context, ns string) error {
+	name := bootjobs.GitOperatorDeploymentName
+	deployInterface := o.KubeClient.AppsV1().Deployments(ns)
+	deploy, err := deployInterface.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to find Deployment %s in namespace %s: %w", name, ns, err)
+	}
+	if deploy.Spec.Template.Annotations == nil {
+		deploy.Spec.Template.Annotations = map[string]string{}
+	}
+	deploy.Spec.Template.Annotations[AnnotationRestartedAt] = time.Now().Format(time.RFC3339)
+	_, err = deployInterface.Update(ctx, deploy, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to restart Deployment %s in namespace %s: %w", name, ns, err)
+	}
+	log.Logger().Infof("restarted the git operator Deployment %s in namespace %s", info(name), info(ns))
+	return nil
+}
@@ -0,0 +1,131 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/operatorsecrets"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/jxenv"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// SecretModeHelm passes the git credentials to the git operator chart which creates the Secret
+	SecretModeHelm = "helm"
+
+	// SecretModeKube creates or updates the git operator Secret via the kubernetes API so no credentials are passed to helm
+	SecretModeKube = "kube"
+
+	// SecretModeExisting uses an existing Secret or a Secret populated by an ExternalSecret so no credentials are passed to helm
+	SecretModeExisting = "existing"
+)
+
+// SecretModes the possible values of the secret mode
+var SecretModes = []string{SecretModeHelm, SecretModeKube, SecretModeExisting}
+
+// passCredentialsToChart returns true if the git credentials are passed to the chart as helm values
+func (o *Options) passCredentialsToChart() bool {
+	return o.SecretMode == "" || o.SecretMode == SecretModeHelm
+}
+
+func (o *Options) validateSecretMode() error {
+	if o.SecretMode == "" {
+		o.SecretMode = SecretModeHelm
+	}
+	if stringhelpers.StringArrayIndex(SecretModes, o.SecretMode) < 0 {
+		return options.InvalidOption("secret-mode", o.SecretMode, SecretModes)
+	}
+	if o.SecretName == "" {
+		o.SecretName = operatorsecrets.DefaultSecretName
+	}
+	return nil
+}
+
+// ensureCredentialsSecret creates or verifies the git operator Secret if the credentials are not passed to the chart
+func (o *Options) ensureCredentialsSecret() error {
+	if o.passCredentialsToChart() {
+		return nil
+	}
+	ns := o.Namespace
+	name := o.SecretName
+	if o.SecretMode == SecretModeKube && o.GitURL == "" {
+		return options.MissingOption("url")
+	}
+	if o.DryRun {
+		if o.SecretMode == SecretModeKube {
+			log.Logger().Infof("would create or update Secret %s in namespace %s with the git credentials for %s", termcolor.ColorInfo(name), termcolor.ColorInfo(ns), termcolor.ColorInfo(o.GitURL))
+		} else {
+			log.Logger().Infof("would verify the Secret %s exists in namespace %s", termcolor.ColorInfo(name), termcolor.ColorInfo(ns))
+		}
+		return nil
+	}
+
+	var err error
+	o.KubeClient, err = o.KubeConfig.LazyCreateKubeClient(o.KubeClient)
+	if err != nil {
+		return fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+	if o.SecretMode == SecretModeExisting {
+		return o.verifyExistingSecret(ns, name)
+	}
+
+	if !o.SkipNamespaceCreation {
+		err = jxenv.EnsureNamespaceCreated(o.KubeClient, ns, nil, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create namespace %s: %w", ns, err)
+		}
+	}
+	secret := operatorsecrets.NewSecret(ns, name, o.gitCredentials())
+	_, err = operatorsecrets.EnsureSecret(o.KubeClient, secret)
+	if err != nil {
+		return err
+	}
+	log.Logger().Infof("created or updated Secret %s in namespace %s with the git credentials", termcolor.ColorInfo(name), termcolor.ColorInfo(ns))
+	return nil
+}
+
+// verifyExistingSecret verifies there is a Secret or ExternalSecret for the given name
+func (o *Options) verifyExistingSecret(ns, name string) error {
+	secret, err := o.KubeClient.CoreV1().Secrets(ns).Get(context.TODO(), name, metav1.GetOptions{})
+	if err == nil {
+		err = operatorsecrets.EnsureSecretLabel(o.KubeClient, secret)
+		if err != nil {
+			return err
+		}
+		log.Logger().Infof("using the existing Secret %s in namespace %s", termcolor.ColorInfo(name), termcolor.ColorInfo(ns))
+		return nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to find Secret %s in namespace %s: %w", name, ns, err)
+	}
+
+	o.DynamicClient, err = o.KubeConfig.LazyCreateDynamicClient(o.DynamicClient)
+	if err != nil {
+		return fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+	found, err := operatorsecrets.FindExternalSecret(o.DynamicClient, ns, name)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("there is no Secret or ExternalSecret called %s in namespace %s", name, ns)
+	}
+	log.Logger().Infof("using the ExternalSecret %s in namespace %s. Please make sure its template adds the label %s to the Secret", termcolor.ColorInfo(name), termcolor.ColorInfo(ns), termcolor.ColorInfo("git-operator.jenkins.io/kind: git-operator"))
+	return nil
+}
+
+// gitCredentials returns the git credentials to store in the git operator Secret
+func (o *Options) gitCredentials() *operatorsecrets.Credentials {
+	creds := &operatorsecrets.Credentials{
+		URL:      o.GitURL,
+		Username: o.GitUserName,
+		Password: o.GitToken,
+	}
+	return creds
+}
@@ -0,0 +1,385 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/bootjobs"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/common"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/operatorsecrets"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/jobs"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/table"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+
+	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// JobStatusRunning the boot Job is still running
+	JobStatusRunning = "Running"
+
+	// JobStatusSucceeded the boot Job succeeded
+	JobStatusSucceeded = "Succeeded"
+
+	// JobStatusFailed the boot Job failed
+	JobStatusFailed = "Failed"
+)
+
+// OutputFormats the supported output formats
+var OutputFormats = []string{"json", "yaml"}
+
+// Options contains the command line arguments for this command
+type Options struct {
+	options.BaseOptions
+
+	Namespace   string
+	JobSelector string
+	Output      string
+	Out         io.Writer
+	KubeClient  kubernetes.Interface
+	Status      *Status
+}
+
+// Status the status of the git operator and its repositories
+type Status struct {
+	Namespace    string              `json:"namespace"`
+	Deployment   DeploymentStatus    `json:"deployment"`
+	LatestJob    *JobStatus          `json:"latestJob,omitempty"`
+	Repositories []*RepositoryStatus `json:"repositories"`
+}
+
+// DeploymentStatus the status of the git operator Deployment
+type DeploymentStatus struct {
+	Name          string `json:"name"`
+	Image         string `json:"image"`
+	Version       string `json:"version,omitempty"`
+	Chart         string `json:"chart,omitempty"`
+	Replicas      int32  `json:"replicas"`
+	ReadyReplicas int32  `json:"readyReplicas"`
+	Ready         bool   `json:"ready"`
+}
+
+// RepositoryStatus the status of a git repository the git operator boots
+type RepositoryStatus struct {
+	SecretName    string     `json:"secretName"`
+	URL           string     `json:"url,omitempty"`
+	Username      string     `json:"username,omitempty"`
+	Token         string     `json:"token,omitempty"`
+	Namespace     string     `json:"namespace,omitempty"`
+	LastCommitSHA string     `json:"lastCommitSHA,omitempty"`
+	LatestJob     *JobStatus `json:"latestJob,omitempty"`
+}
+
+// JobStatus the status of a boot Job
+type JobStatus struct {
+	Name      string     `json:"name"`
+	Status    string     `json:"status"`
+	CommitSHA string     `json:"commitSHA,omitempty"`
+	Started   *time.Time `json:"started,omitempty"`
+	Completed *time.Time `json:"completed,omitempty"`
+}
+
+var (
+	info = termcolor.ColorInfo
+
+	cmdLong = templates.LongDesc(`
+		Displays the status of the git operator and the git repositories it boots
+
+		Reports the git operator image, version and readiness, the git URL, username and masked token of each git operator Secret along with the latest boot Job. The latest boot Job of a repository is only reported if it is the only repository whose boot Jobs run in its namespace as the boot Jobs are not labelled with their repository
+`)
+
+	cmdExample = templates.Examples(`
+* displays the status of the git operator
+` + bashExample("operator status") + `
+* displays the status of the git operator as JSON
+` + bashExample("operator status -o json") + `
+`)
+)
+
+// bashExample returns markdown for a bash script expression
+func bashExample(cli string) string {
+	return fmt.Sprintf("\n```bash \n%s %s\n```\n", common.BinaryName, cli)
+}
+
+// NewCmdStatus creates the new command
+func NewCmdStatus() (*cobra.Command, *Options) {
+	o := &Options{}
+	command := &cobra.Command{
+		Use:     "status",
+		Short:   "displays the status of the git operator and the git repositories it boots",
+		Aliases: []string{"describe"},
+		Long:    cmdLong,
+		Example: cmdExample,
+		Run: func(command *cobra.Command, args []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	command.Flags().StringVarP(&o.Namespace, "namespace", "n", "", "the namespace the git operator is installed in. If not specified it will look in: jx-git-operator and jx")
+	command.Flags().StringVarP(&o.JobSelector, "selector", "s", bootjobs.DefaultJobSelector, "the selector of the boot Jobs")
+	command.Flags().StringVarP(&o.Output, "output", "o", "", fmt.Sprintf("the output format. Possible values: %s. If not specified a summary is displayed", strings.Join(OutputFormats, ", ")))
+
+	o.BaseOptions.AddBaseFlags(command)
+
+	return command, o
+}
+
+// Run displays the status
+func (o *Options) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return err
+	}
+
+	o.Status, err = o.GetStatus()
+	if err != nil {
+		return err
+	}
+
+	switch o.Output {
+	case "json":
+		data, err := json.MarshalIndent(o.Status, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal status to JSON: %w", err)
+		}
+		fmt.Fprintln(o.Out, string(data))
+	case "yaml":
+		data, err := yaml.Marshal(o.Status)
+		if err != nil {
+			return fmt.Errorf("failed to marshal status to YAML: %w", err)
+		}
+		fmt.Fprint(o.Out, string(data))
+	default:
+		o.render()
+	}
+	return nil
+}
+
+// Validate verifies the settings are correct and we can lazy create any required resources
+func (o *Options) Validate() error {
+	if o.Output != "" && o.Output != "json" && o.Output != "yaml" {
+		return options.InvalidOption("output", o.Output, OutputFormats)
+	}
+	if o.JobSelector == "" {
+		o.JobSelector = bootjobs.DefaultJobSelector
+	}
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+	var err error
+	o.KubeClient, err = kube.LazyCreateKubeClientWithMandatory(o.KubeClient, true)
+	if err != nil {
+		return fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+	return nil
+}
+
+// GetStatus returns the status of the git operator
+func (o *Options) GetStatus() (*Status, error) {
+	ns, err := bootjobs.FindGitOperatorNamespace(o.KubeClient, o.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the git operator namespace: %w", err)
+	}
+
+	ctx := context.TODO()
+	name := bootjobs.GitOperatorDeploymentName
+	deploy, err := o.KubeClient.AppsV1().Deployments(ns).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find Deployment %s in namespace %s: %w", name, ns, err)
+	}
+	answer := &Status{
+		Namespace:  ns,
+		Deployment: toDeploymentStatus(deploy),
+	}
+
+	secrets, err := o.KubeClient.CoreV1().Secrets(ns).List(ctx, metav1.ListOptions{
+		LabelSelector: bootjobs.GitOperatorSecretSelector,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Secrets in namespace %s with selector %s: %w", ns, bootjobs.GitOperatorSecretSelector, err)
+	}
+	sortedJobs, err := bootjobs.GetSortedJobs(o.KubeClient, ns, o.JobSelector, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get jobs: %w", err)
+	}
+	if len(sortedJobs) > 0 {
+		answer.LatestJob = toJobStatus(&sortedJobs[0])
+	}
+
+	// the boot Jobs are not labelled with their repository so we can only tell which Jobs belong to a
+	// repository if it is the only repository whose Jobs run in the namespace
+	repoCounts := map[string]int{}
+	for i := range secrets.Items {
+		repoCounts[jobNamespace(operatorsecrets.GetCredentials(&secrets.Items[i]), ns)]++
+	}
+
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		creds := operatorsecrets.GetCredentials(secret)
+		repo := &RepositoryStatus{
+			SecretName: secret.Name,
+			URL:        creds.URL,
+			Username:   creds.Username,
+			Token:      MaskToken(creds.Password),
+			Namespace:  creds.Namespace,
+		}
+		jobNS := jobNamespace(creds, ns)
+		if repoCounts[jobNS] == 1 {
+			repoJobs := sortedJobs
+			if jobNS != ns {
+				repoJobs, err = bootjobs.GetSortedJobs(o.KubeClient, jobNS, o.JobSelector, "")
+				if err != nil {
+					return nil, fmt.Errorf("failed to get jobs in namespace %s: %w", jobNS, err)
+				}
+			}
+			if len(repoJobs) > 0 {
+				repo.LatestJob = toJobStatus(&repoJobs[0])
+				repo.LastCommitSHA = repo.LatestJob.CommitSHA
+			}
+		}
+		answer.Repositories = append(answer.Repositories, repo)
+	}
+	return answer, nil
+}
+
+func (o *Options) render() {
+	s := o.Status
+	d := &s.Deployment
+	ready := termcolor.ColorWarning("not ready")
+	if d.Ready {
+		ready = info("ready")
+	}
+	t := table.CreateTable(o.Out)
+	t.AddRow("NAMESPACE", info(s.Namespace))
+	t.AddRow("IMAGE", d.Image)
+	if d.Version != "" {
+		t.AddRow("VERSION", d.Version)
+	}
+	if d.Chart != "" {
+		t.AddRow("CHART", d.Chart)
+	}
+	t.AddRow("STATUS", fmt.Sprintf("%s (%d/%d)", ready, d.ReadyReplicas, d.Replicas))
+	if s.LatestJob != nil {
+		t.AddRow("LATEST JOB", fmt.Sprintf("%s %s", s.LatestJob.Name, colorJobStatus(s.LatestJob.Status)))
+	}
+	t.Render()
+
+	fmt.Fprintln(o.Out)
+	if len(s.Repositories) == 0 {
+		fmt.Fprintf(o.Out, "there are no git operator Secrets in namespace %s\n", s.Namespace)
+		return
+	}
+	t = table.CreateTable(o.Out)
+	t.AddRow("SECRET", "URL", "USERNAME", "TOKEN", "LAST SHA", "LATEST JOB", "JOB STATUS")
+	for _, r := range s.Repositories {
+		jobName := ""
+		jobStatus := ""
+		if r.LatestJob != nil {
+			jobName = r.LatestJob.Name
+			jobStatus = colorJobStatus(r.LatestJob.Status)
+		}
+		t.AddRow(r.SecretName, r.URL, r.Username, r.Token, r.LastCommitSHA, jobName, jobStatus)
+	}
+	t.Render()
+}
+
+// MaskToken masks the token only showing the last few characters so that users can tell which token is used
+func MaskToken(token string) string {
+	if token == "" {
+		return ""
+	}
+	if len(token) <= 8 {
+		return "****"
+	}
+	return "****" + token[len(token)-4:]
+}
+
+func toDeploymentStatus(deploy *appsv1.Deployment) DeploymentStatus {
+	answer := DeploymentStatus{
+		Name:          deploy.Name,
+		Replicas:      1,
+		ReadyReplicas: deploy.Status.ReadyReplicas,
+	}
+	if deploy.Spec.Replicas != nil {
+		answer.Replicas = *deploy.Spec.Replicas
+	}
+	containers := deploy.Spec.Template.Spec.Containers
+	if len(containers) > 0 {
+		answer.Image = containers[0].Image
+	}
+	labels := deploy.Labels
+	if labels != nil {
+		answer.Version = labels["app.kubernetes.io/version"]
+		answer.Chart = labels["helm.sh/chart"]
+		if answer.Chart == "" {
+			answer.Chart = labels["chart"]
+		}
+	}
+	if answer.Version == "" {
+		idx := strings.LastIndex(answer.Image, ":")
+		if idx > 0 && !strings.Contains(answer.Image[idx:], "/") {
+			answer.Version = answer.Image[idx+1:]
+		}
+	}
+	answer.Ready = answer.ReadyReplicas > 0 && answer.ReadyReplicas >= answer.Replicas
+	return answer
+}
+
+// jobNamespace returns the namespace the boot Jobs of the git operator Secret run in
+func jobNamespace(creds *operatorsecrets.Credentials, defaultNamespace string) string {
+	if creds.Namespace == "" {
+		return defaultNamespace
+	}
+	return creds.Namespace
+}
+
+func toJobStatus(job *batchv1.Job) *JobStatus {
+	answer := &JobStatus{
+		Name:   job.Name,
+		Status: JobStatusRunning,
+	}
+	if job.Labels != nil {
+		answer.CommitSHA = job.Labels[bootjobs.LabelCommitSHA]
+	}
+	if jobs.IsJobFinished(job) {
+		if jobs.IsJobSucceeded(job) {
+			answer.Status = JobStatusSucceeded
+		} else {
+			answer.Status = JobStatusFailed
+		}
+	}
+	if job.Status.StartTime != nil {
+		t := job.Status.StartTime.Time
+		answer.Started = &t
+	}
+	if job.Status.CompletionTime != nil {
+		t := job.Status.CompletionTime.Time
+		answer.Completed = &t
+	}
+	return answer
+}
+
+func colorJobStatus(status string) string {
+	switch status {
+	case JobStatusSucceeded:
+		return info(status)
+	case JobStatusFailed:
+		return termcolor.ColorError(status)
+	default:
+		return termcolor.ColorStatus(status)
+	}
+}
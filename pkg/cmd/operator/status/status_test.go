@@ -0,0 +1,104 @@
+package status_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/bootjobs"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/cmd/operator/status"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/operatorsecrets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestStatus(t *testing.T) {
+	ns := "jx-git-operator"
+	secret := operatorsecrets.NewSecret(ns, operatorsecrets.DefaultSecretName, &operatorsecrets.Credentials{
+		URL:      "https://github.com/myorg/environment-mycluster-dev.git",
+		Username: "mybot",
+		Password: "ghp_abcdefghijklmnop1234",
+	})
+	kubeClient := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      bootjobs.GitOperatorDeploymentName,
+				Namespace: ns,
+				Labels: map[string]string{
+					"chart": "jx-git-operator-0.1.2",
+				},
+			},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  "jx-git-operator",
+								Image: "ghcr.io/jenkins-x/jx-git-operator:0.1.2",
+							},
+						},
+					},
+				},
+			},
+			Status: appsv1.DeploymentStatus{
+				ReadyReplicas: 1,
+			},
+		},
+		secret,
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "jx-boot-abc",
+				Namespace: ns,
+				Labels: map[string]string{
+					"app":                   "jx-boot",
+					bootjobs.LabelCommitSHA: "abc123",
+				},
+			},
+			Status: batchv1.JobStatus{
+				Succeeded: 1,
+				Conditions: []batchv1.JobCondition{
+					{
+						Type:   batchv1.JobComplete,
+						Status: corev1.ConditionTrue,
+					},
+				},
+			},
+		},
+	)
+
+	out := &bytes.Buffer{}
+	_, o := status.NewCmdStatus()
+	o.KubeClient = kubeClient
+	o.Output = "json"
+	o.Out = out
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run status")
+
+	s := &status.Status{}
+	err = json.Unmarshal(out.Bytes(), s)
+	require.NoError(t, err, "failed to parse output %s", out.String())
+
+	assert.Equal(t, ns, s.Namespace, "namespace")
+	assert.Equal(t, "ghcr.io/jenkins-x/jx-git-operator:0.1.2", s.Deployment.Image, "image")
+	assert.Equal(t, "0.1.2", s.Deployment.Version, "version")
+	assert.Equal(t, "jx-git-operator-0.1.2", s.Deployment.Chart, "chart")
+	assert.True(t, s.Deployment.Ready, "ready")
+
+	require.Len(t, s.Repositories, 1, "repositories")
+	r := s.Repositories[0]
+	assert.Equal(t, "https://github.com/myorg/environment-mycluster-dev.git", r.URL, "url")
+	assert.Equal(t, "mybot", r.Username, "username")
+	assert.Equal(t, "****1234", r.Token, "token")
+	assert.Equal(t, "abc123", r.LastCommitSHA, "last commit SHA")
+	require.NotNil(t, r.LatestJob, "latest job")
+	assert.Equal(t, status.JobStatusSucceeded, r.LatestJob.Status, "job status")
+	require.NotNil(t, s.LatestJob, "latest job of the git operator namespace")
+	assert.Equal(t, "jx-boot-abc", s.LatestJob.Name, "latest job name")
+	assert.NotContains(t, out.String(), "ghp_abcdefghijklmnop1234", "should not output the token")
+}
@@ -0,0 +1,266 @@
+package uninstall
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/bootjobs"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/common"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/plugins/helmplugin"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/input"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/input/inputfactory"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Options contains the command line arguments for this command
+type Options struct {
+	options.BaseOptions
+
+	Namespace       string
+	ReleaseName     string
+	HelmBin         string
+	JobSelector     string
+	DeleteSecrets   bool
+	DeleteJobs      bool
+	DeleteNamespace bool
+	DryRun          bool
+	CommandRunner   cmdrunner.CommandRunner
+	KubeClient      kubernetes.Interface
+	Input           input.Interface
+}
+
+var (
+	info = termcolor.ColorInfo
+
+	cmdLong = templates.LongDesc(`
+		Uninstalls the git operator from a cluster
+
+		Removes the git operator helm release and optionally the git operator Secrets, the boot Jobs and the namespace
+`)
+
+	cmdExample = templates.Examples(`
+* uninstalls the git operator helm release
+` + bashExample("operator uninstall") + `
+* uninstalls the git operator and removes the git credentials Secrets and boot Jobs
+` + bashExample("operator uninstall --delete-secrets --delete-jobs") + `
+* uninstalls the git operator and removes its namespace
+` + bashExample("operator uninstall --delete-namespace") + `
+* display what would be removed without removing anything
+` + bashExample("operator uninstall --delete-secrets --delete-jobs --dry-run") + `
+`)
+)
+
+// bashExample returns markdown for a bash script expression
+func bashExample(cli string) string {
+	return fmt.Sprintf("\n```bash \n%s %s\n```\n", common.BinaryName, cli)
+}
+
+// NewCmdUninstall creates the new command
+func NewCmdUninstall() (*cobra.Command, *Options) {
+	o := &Options{}
+	command := &cobra.Command{
+		Use:     "uninstall",
+		Short:   "uninstalls the git operator from a cluster",
+		Aliases: []string{"remove", "delete"},
+		Long:    cmdLong,
+		Example: cmdExample,
+		Run: func(command *cobra.Command, args []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	command.Flags().StringVarP(&o.Namespace, "namespace", "n", common.DefaultOperatorNamespace, "the namespace the git operator is installed in")
+	command.Flags().StringVarP(&o.ReleaseName, "name", "", common.DefaultOperatorReleaseName, "the helm release name of the git operator")
+	command.Flags().StringVarP(&o.JobSelector, "selector", "s", bootjobs.DefaultJobSelector, "the selector of the boot Jobs to delete if --delete-jobs is enabled")
+	command.Flags().BoolVarP(&o.DeleteSecrets, "delete-secrets", "", false, "if enabled also delete the git operator Secrets containing the git credentials")
+	command.Flags().BoolVarP(&o.DeleteJobs, "delete-jobs", "", false, "if enabled also delete the boot Jobs")
+	command.Flags().BoolVarP(&o.DeleteNamespace, "delete-namespace", "", false, "if enabled also delete the namespace the git operator was installed in")
+	command.Flags().BoolVarP(&o.DryRun, "dry-run", "", false, "if enabled just display what would be removed but don't actually do anything")
+
+	o.BaseOptions.AddBaseFlags(command)
+
+	return command, o
+}
+
+// Run uninstalls the git operator
+func (o *Options) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return err
+	}
+
+	ns := o.Namespace
+	if !o.DryRun && !o.BatchMode {
+		flag, err := o.Input.Confirm(fmt.Sprintf("are you sure you want to uninstall the git operator from namespace %s?", ns), false, "the git operator will no longer synchronise the environment git repository into the cluster")
+		if err != nil {
+			return fmt.Errorf("failed to get confirmation of the git operator uninstall: %w", err)
+		}
+		if !flag {
+			return nil
+		}
+	}
+
+	err = o.uninstallRelease()
+	if err != nil {
+		return err
+	}
+	if o.DeleteSecrets {
+		err = o.deleteSecrets(ns)
+		if err != nil {
+			return err
+		}
+	}
+	if o.DeleteJobs {
+		err = o.deleteJobs(ns)
+		if err != nil {
+			return err
+		}
+	}
+	if o.DeleteNamespace {
+		err = o.deleteNamespace(ns)
+		if err != nil {
+			return err
+		}
+	}
+	if !o.DryRun {
+		log.Logger().Infof("uninstalled the git operator from namespace %s", info(ns))
+	}
+	return nil
+}
+
+// Validate verifies the settings are correct and we can lazy create any required resources
+func (o *Options) Validate() error {
+	if o.Namespace == "" {
+		return options.MissingOption("namespace")
+	}
+	if o.ReleaseName == "" {
+		return options.MissingOption("name")
+	}
+	if o.CommandRunner == nil {
+		o.CommandRunner = cmdrunner.QuietCommandRunner
+	}
+	var err error
+	if o.HelmBin == "" {
+		o.HelmBin, err = helmplugin.GetHelm3Binary()
+		if err != nil {
+			return err
+		}
+	}
+	o.KubeClient, err = kube.LazyCreateKubeClientWithMandatory(o.KubeClient, true)
+	if err != nil {
+		return fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+	if o.Input == nil {
+		o.Input = inputfactory.NewInput(&o.BaseOptions)
+	}
+	return nil
+}
+
+func (o *Options) uninstallRelease() error {
+	c := &cmdrunner.Command{
+		Name: o.HelmBin,
+		Args: []string{"uninstall", o.ReleaseName, "--namespace", o.Namespace},
+	}
+	commandLine := fmt.Sprintf("%s %s", c.Name, strings.Join(c.Args, " "))
+	if o.DryRun {
+		log.Logger().Infof("\nTo uninstall the git operator run this command:\n\n%s\n\n", info(commandLine))
+		return nil
+	}
+
+	log.Logger().Infof("running command:\n\n%s\n\n", info(commandLine))
+
+	text, err := o.CommandRunner(c)
+	if err != nil {
+		if strings.Contains(text, "not found") || strings.Contains(err.Error(), "not found") {
+			log.Logger().Warnf("there is no helm release %s in namespace %s", o.ReleaseName, o.Namespace)
+			return nil
+		}
+		return fmt.Errorf("failed to run command %s: %w", commandLine, err)
+	}
+	return nil
+}
+
+func (o *Options) deleteSecrets(ns string) error {
+	ctx := context.TODO()
+	secretInterface := o.KubeClient.CoreV1().Secrets(ns)
+	list, err := secretInterface.List(ctx, metav1.ListOptions{
+		LabelSelector: bootjobs.GitOperatorSecretSelector,
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to list Secrets in namespace %s with selector %s: %w", ns, bootjobs.GitOperatorSecretSelector, err)
+	}
+	if list == nil || len(list.Items) == 0 {
+		log.Logger().Infof("there are no git operator Secrets in namespace %s", info(ns))
+		return nil
+	}
+	for i := range list.Items {
+		name := list.Items[i].Name
+		if o.DryRun {
+			log.Logger().Infof("would delete Secret %s in namespace %s", info(name), info(ns))
+			continue
+		}
+		err = secretInterface.Delete(ctx, name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete Secret %s in namespace %s: %w", name, ns, err)
+		}
+		log.Logger().Infof("deleted Secret %s in namespace %s", info(name), info(ns))
+	}
+	return nil
+}
+
+func (o *Options) deleteJobs(ns string) error {
+	sortedJobs, err := bootjobs.GetSortedJobs(o.KubeClient, ns, o.JobSelector, "")
+	if err != nil {
+		return fmt.Errorf("failed to get jobs: %w", err)
+	}
+	if len(sortedJobs) == 0 {
+		log.Logger().Infof("there are no boot Jobs in namespace %s with selector %s", info(ns), info(o.JobSelector))
+		return nil
+	}
+
+	ctx := context.TODO()
+	// lets make sure we remove the pods of the jobs too
+	propagation := metav1.DeletePropagationBackground
+	for i := range sortedJobs {
+		name := sortedJobs[i].Name
+		if o.DryRun {
+			log.Logger().Infof("would delete Job %s in namespace %s", info(name), info(ns))
+			continue
+		}
+		err = o.KubeClient.BatchV1().Jobs(ns).Delete(ctx, name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete Job %s in namespace %s: %w", name, ns, err)
+		}
+		log.Logger().Infof("deleted Job %s in namespace %s", info(name), info(ns))
+	}
+	return nil
+}
+
+func (o *Options) deleteNamespace(ns string) error {
+	if o.DryRun {
+		log.Logger().Infof("would delete namespace %s", info(ns))
+		return nil
+	}
+	err := o.KubeClient.CoreV1().Namespaces().Delete(context.TODO(), ns, metav1.DeleteOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Logger().Warnf("there is no namespace %s", ns)
+			return nil
+		}
+		return fmt.Errorf("failed to delete namespace %s: %w", ns, err)
+	}
+	log.Logger().Infof("deleted namespace %s", info(ns))
+	return nil
+}
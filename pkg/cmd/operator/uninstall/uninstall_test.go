@@ -0,0 +1,109 @@
+package uninstall_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/bootjobs"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/cmd/operator/uninstall"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner/fakerunner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestUninstall(t *testing.T) {
+	ns := "jx-git-operator"
+
+	testCases := []struct {
+		name          string
+		dryRun        bool
+		deleteSecrets bool
+		deleteJobs    bool
+		expectSecrets int
+		expectJobs    int
+	}{
+		{
+			name:          "release-only",
+			expectSecrets: 2,
+			expectJobs:    1,
+		},
+		{
+			name:          "secrets-and-jobs",
+			deleteSecrets: true,
+			deleteJobs:    true,
+			expectSecrets: 1,
+			expectJobs:    0,
+		},
+		{
+			name:          "dry-run",
+			dryRun:        true,
+			deleteSecrets: true,
+			deleteJobs:    true,
+			expectSecrets: 2,
+			expectJobs:    1,
+		},
+	}
+
+	for _, tc := range testCases {
+		kubeClient := fake.NewSimpleClientset(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "jx-boot",
+					Namespace: ns,
+					Labels: map[string]string{
+						bootjobs.LabelGitOperatorKind: bootjobs.GitOperatorKind,
+					},
+				},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "another",
+					Namespace: ns,
+				},
+			},
+			&batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "jx-boot-abc",
+					Namespace: ns,
+					Labels: map[string]string{
+						"app": "jx-boot",
+					},
+				},
+			},
+		)
+
+		runner := &fakerunner.FakeRunner{}
+		_, o := uninstall.NewCmdUninstall()
+		o.CommandRunner = runner.Run
+		o.HelmBin = "helm"
+		o.KubeClient = kubeClient
+		o.BatchMode = true
+		o.DryRun = tc.dryRun
+		o.DeleteSecrets = tc.deleteSecrets
+		o.DeleteJobs = tc.deleteJobs
+
+		err := o.Run()
+		require.NoError(t, err, "failed to run test %s", tc.name)
+
+		if tc.dryRun {
+			runner.ExpectResults(t)
+		} else {
+			runner.ExpectResults(t, fakerunner.FakeResult{
+				CLI: "helm uninstall jxgo --namespace jx-git-operator",
+			})
+		}
+
+		ctx := context.TODO()
+		secrets, err := kubeClient.CoreV1().Secrets(ns).List(ctx, metav1.ListOptions{})
+		require.NoError(t, err, "failed to list secrets")
+		assert.Len(t, secrets.Items, tc.expectSecrets, "secrets for test %s", tc.name)
+
+		jobList, err := kubeClient.BatchV1().Jobs(ns).List(ctx, metav1.ListOptions{})
+		require.NoError(t, err, "failed to list jobs")
+		assert.Len(t, jobList.Items, tc.expectJobs, "jobs for test %s", tc.name)
+	}
+}
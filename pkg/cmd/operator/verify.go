@@ -0,0 +1,48 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/gitcreds"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/giturl"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+)
+
+// verifyGitCredentials verifies the git credentials can read the git repository before we install the git operator
+// so that we fail fast rather than the boot Job failing to clone the repository inside the cluster
+func (o *Options) verifyGitCredentials() error {
+	if o.NoVerify || o.GitURL == "" || o.GitToken == "" {
+		return nil
+	}
+	_, err := o.createScmClient()
+	if err != nil {
+		log.Logger().Debugf("could not create an SCM client so verifying the git credentials via git ls-remote: %s", err.Error())
+	}
+	return gitcreds.VerifyCredentials(context.TODO(), o.ScmClient, o.CommandRunner, o.GitURL, o.GitUserName, o.GitToken)
+}
+
+// createScmClient lazily creates the SCM client for the git repository
+func (o *Options) createScmClient() (*giturl.GitRepository, error) {
+	repo, err := giturl.ParseGitURL(o.GitURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse git URL %s: %w", o.GitURL, err)
+	}
+	if o.ScmClient != nil {
+		return repo, nil
+	}
+	kind := o.GitKind
+	if kind == "" {
+		// lets try default the git kind from the requirements if we are inside a cluster git repository
+		requirements, _, err := jxcore.LoadRequirementsConfig(o.Dir, false)
+		if err == nil && requirements != nil {
+			kind = requirements.Spec.Cluster.GitKind
+		}
+	}
+	o.ScmClient, _, err = gitcreds.NewScmClient(o.HTTPClient, o.GitURL, kind, o.GitUserName, o.GitToken)
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
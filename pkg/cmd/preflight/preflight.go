@@ -0,0 +1,312 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/bootjobs"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/common"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/table"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+
+	"github.com/spf13/cobra"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// StatusPass the check passed
+	StatusPass = "pass"
+
+	// StatusWarn the check passed with a warning
+	StatusWarn = "warn"
+
+	// StatusFail the check failed
+	StatusFail = "fail"
+
+	// MinimumKubernetesVersion the minimum kubernetes minor version supported
+	MinimumKubernetesVersion = 24
+
+	// annotationDefaultStorageClass the annotation on the default StorageClass
+	annotationDefaultStorageClass = "storageclass.kubernetes.io/is-default-class"
+)
+
+// Options contains the command line arguments for this command
+type Options struct {
+	options.BaseOptions
+
+	Dir        string
+	Namespace  string
+	Out        io.Writer
+	KubeClient kubernetes.Interface
+	Results    []*Result
+}
+
+// Result the result of a preflight check
+type Result struct {
+	Name    string
+	Status  string
+	Message string
+}
+
+var (
+	cmdLong = templates.LongDesc(`
+		Checks the cluster is ready to install the git operator and boot JayeX
+
+		Verifies the kubernetes version, RBAC permissions, existing git operator installations, storage classes and the provider in the requirements. Returns an error if any check fails
+`)
+
+	cmdExample = templates.Examples(`
+* runs the preflight checks against the current cluster
+` + bashExample("preflight") + `
+* runs the preflight checks using the requirements in a cluster git repository
+` + bashExample("preflight --dir ./environment-mycluster-dev") + `
+`)
+
+	// rbacChecks the resources the current user needs to be able to create to boot JayeX
+	rbacChecks = []authorizationv1.ResourceAttributes{
+		{Verb: "create", Resource: "namespaces"},
+		{Verb: "create", Group: "apiextensions.k8s.io", Resource: "customresourcedefinitions"},
+		{Verb: "create", Group: "rbac.authorization.k8s.io", Resource: "clusterroles"},
+	}
+
+	// providerServerVersions the text found in the kubernetes server version of the managed kubernetes providers
+	providerServerVersions = map[string]string{
+		"gke": "-gke.",
+		"eks": "-eks-",
+	}
+)
+
+// bashExample returns markdown for a bash script expression
+func bashExample(cli string) string {
+	return fmt.Sprintf("\n```bash \n%s %s\n```\n", common.BinaryName, cli)
+}
+
+// NewCmdPreflight creates the new command
+func NewCmdPreflight() (*cobra.Command, *Options) {
+	o := &Options{}
+	command := &cobra.Command{
+		Use:     "preflight",
+		Short:   "checks the cluster is ready to install the git operator",
+		Aliases: []string{"preflight-check", "check"},
+		Long:    cmdLong,
+		Example: cmdExample,
+		Run: func(command *cobra.Command, args []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	command.Flags().StringVarP(&o.Dir, "dir", "d", ".", "the directory containing the jx-requirements.yml file")
+	command.Flags().StringVarP(&o.Namespace, "namespace", "n", common.DefaultOperatorNamespace, "the namespace the git operator is going to be installed in")
+
+	o.BaseOptions.AddBaseFlags(command)
+
+	return command, o
+}
+
+// Run runs the preflight checks
+func (o *Options) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return err
+	}
+
+	o.Results = nil
+	o.checkServerVersion()
+	o.checkRBAC()
+	o.checkGitOperator()
+	o.checkStorageClasses()
+	o.checkRequirements()
+
+	t := table.CreateTable(o.Out)
+	t.AddRow("CHECK", "STATUS", "MESSAGE")
+	failed := 0
+	for _, r := range o.Results {
+		status := termcolor.ColorInfo(r.Status)
+		switch r.Status {
+		case StatusWarn:
+			status = termcolor.ColorWarning(r.Status)
+		case StatusFail:
+			status = termcolor.ColorError(r.Status)
+			failed++
+		}
+		t.AddRow(r.Name, status, r.Message)
+	}
+	t.Render()
+
+	if failed > 0 {
+		return fmt.Errorf("%d preflight checks failed", failed)
+	}
+	return nil
+}
+
+// Validate verifies the settings are correct and we can lazy create any required resources
+func (o *Options) Validate() error {
+	if o.Namespace == "" {
+		o.Namespace = common.DefaultOperatorNamespace
+	}
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+	var err error
+	o.KubeClient, err = kube.LazyCreateKubeClientWithMandatory(o.KubeClient, true)
+	if err != nil {
+		return fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+	return nil
+}
+
+func (o *Options) addResult(name, status, message string, args ...interface{}) {
+	o.Results = append(o.Results, &Result{
+		Name:    name,
+		Status:  status,
+		Message: fmt.Sprintf(message, args...),
+	})
+}
+
+func (o *Options) serverVersion() string {
+	v, err := o.KubeClient.Discovery().ServerVersion()
+	if err != nil || v == nil {
+		return ""
+	}
+	return v.GitVersion
+}
+
+func (o *Options) checkServerVersion() {
+	name := "kubernetes version"
+	v, err := o.KubeClient.Discovery().ServerVersion()
+	if err != nil {
+		o.addResult(name, StatusFail, "failed to find the kubernetes server version: %s", err.Error())
+		return
+	}
+	// some providers add a suffix to the minor version such as 27+
+	minor, err := strconv.Atoi(strings.TrimSuffix(v.Minor, "+"))
+	if err != nil || v.Major != "1" {
+		o.addResult(name, StatusWarn, "could not parse the kubernetes version %s", v.GitVersion)
+		return
+	}
+	if minor < MinimumKubernetesVersion {
+		o.addResult(name, StatusFail, "kubernetes version %s is older than the minimum supported version 1.%d", v.GitVersion, MinimumKubernetesVersion)
+		return
+	}
+	o.addResult(name, StatusPass, "kubernetes version %s", v.GitVersion)
+}
+
+func (o *Options) checkRBAC() {
+	ctx := context.TODO()
+	for i := range rbacChecks {
+		attrs := rbacChecks[i]
+		resource := attrs.Resource
+		if attrs.Group != "" {
+			resource += "." + attrs.Group
+		}
+		name := fmt.Sprintf("%s %s", attrs.Verb, resource)
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &attrs,
+			},
+		}
+		answer, err := o.KubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+		if err != nil {
+			o.addResult(name, StatusFail, "failed to check permission: %s", err.Error())
+			continue
+		}
+		if !answer.Status.Allowed {
+			message := "the current user cannot " + name
+			if answer.Status.Reason != "" {
+				message += ": " + answer.Status.Reason
+			}
+			o.addResult(name, StatusFail, "%s", message)
+			continue
+		}
+		o.addResult(name, StatusPass, "the current user can %s", name)
+	}
+}
+
+// checkGitOperator checks for git operators in the namespaces that bootjobs.FindGitOperatorNamespace searches
+func (o *Options) checkGitOperator() {
+	name := "git operator"
+	ctx := context.TODO()
+	namespaces := []string{"jx", common.DefaultOperatorNamespace}
+	if o.Namespace != "" && o.Namespace != "jx" && o.Namespace != common.DefaultOperatorNamespace {
+		namespaces = append(namespaces, o.Namespace)
+	}
+	var existing []string
+	for _, ns := range namespaces {
+		_, err := o.KubeClient.AppsV1().Deployments(ns).Get(ctx, bootjobs.GitOperatorDeploymentName, metav1.GetOptions{})
+		if err == nil {
+			existing = append(existing, ns)
+			continue
+		}
+		if !apierrors.IsNotFound(err) {
+			o.addResult(name, StatusFail, "failed to find Deployment %s in namespace %s: %s", bootjobs.GitOperatorDeploymentName, ns, err.Error())
+			return
+		}
+	}
+	for _, ns := range existing {
+		if ns != o.Namespace {
+			o.addResult(name, StatusFail, "there is already a git operator in namespace %s which conflicts with installing it into namespace %s", ns, o.Namespace)
+			return
+		}
+	}
+	if len(existing) > 0 {
+		o.addResult(name, StatusWarn, "the git operator is already installed in namespace %s and will be upgraded", o.Namespace)
+		return
+	}
+	o.addResult(name, StatusPass, "no existing git operator found")
+}
+
+func (o *Options) checkStorageClasses() {
+	name := "storage class"
+	list, err := o.KubeClient.StorageV1().StorageClasses().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		o.addResult(name, StatusWarn, "failed to list storage classes: %s", err.Error())
+		return
+	}
+	if len(list.Items) == 0 {
+		o.addResult(name, StatusWarn, "there are no storage classes so persistent volumes cannot be provisioned")
+		return
+	}
+	for i := range list.Items {
+		sc := &list.Items[i]
+		if sc.Annotations[annotationDefaultStorageClass] == "true" {
+			o.addResult(name, StatusPass, "default storage class %s", sc.Name)
+			return
+		}
+	}
+	o.addResult(name, StatusWarn, "there is no default storage class")
+}
+
+func (o *Options) checkRequirements() {
+	name := "requirements provider"
+	requirements, fileName, err := jxcore.LoadRequirementsConfig(o.Dir, false)
+	if err != nil {
+		o.addResult(name, StatusWarn, "could not load the jx-requirements.yml file in dir %s: %s", o.Dir, err.Error())
+		return
+	}
+	provider := requirements.Spec.Cluster.Provider
+	if provider == "" {
+		o.addResult(name, StatusFail, "no cluster provider specified in %s", fileName)
+		return
+	}
+	expected := providerServerVersions[provider]
+	if expected != "" {
+		serverVersion := o.serverVersion()
+		if serverVersion != "" && !strings.Contains(serverVersion, expected) {
+			o.addResult(name, StatusWarn, "the provider %s does not match the kubernetes version %s. Are you connected to the right cluster?", provider, serverVersion)
+			return
+		}
+	}
+	o.addResult(name, StatusPass, "provider %s", provider)
+}
@@ -0,0 +1,112 @@
+package preflight_test
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/bootjobs"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/cmd/preflight"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestPreflight(t *testing.T) {
+	testCases := []struct {
+		name           string
+		serverVersion  string
+		minor          string
+		operatorNS     string
+		expectError    bool
+		expectStatuses map[string]string
+	}{
+		{
+			name:          "pass",
+			serverVersion: "v1.29.1-gke.100",
+			minor:         "29",
+			expectStatuses: map[string]string{
+				"kubernetes version":    preflight.StatusPass,
+				"create namespaces":     preflight.StatusPass,
+				"git operator":          preflight.StatusPass,
+				"storage class":         preflight.StatusPass,
+				"requirements provider": preflight.StatusPass,
+			},
+		},
+		{
+			name:          "conflicting-operator-and-old-kubernetes",
+			serverVersion: "v1.20.1-eks-abc",
+			minor:         "20+",
+			operatorNS:    "jx",
+			expectError:   true,
+			expectStatuses: map[string]string{
+				"kubernetes version":    preflight.StatusFail,
+				"git operator":          preflight.StatusFail,
+				"requirements provider": preflight.StatusWarn,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		kubeClient := fake.NewSimpleClientset(
+			&storagev1.StorageClass{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "standard",
+					Annotations: map[string]string{
+						"storageclass.kubernetes.io/is-default-class": "true",
+					},
+				},
+			},
+		)
+		if tc.operatorNS != "" {
+			_, err := kubeClient.AppsV1().Deployments(tc.operatorNS).Create(context.TODO(), &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      bootjobs.GitOperatorDeploymentName,
+					Namespace: tc.operatorNS,
+				},
+			}, metav1.CreateOptions{})
+			require.NoError(t, err, "failed to create git operator Deployment")
+		}
+		kubeClient.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{
+			Major:      "1",
+			Minor:      tc.minor,
+			GitVersion: tc.serverVersion,
+		}
+		kubeClient.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+			review.Status.Allowed = true
+			return true, review, nil
+		})
+
+		out := &bytes.Buffer{}
+		_, o := preflight.NewCmdPreflight()
+		o.Dir = filepath.Join("test_data", "gke")
+		o.KubeClient = kubeClient
+		o.Out = out
+
+		err := o.Run()
+		if tc.expectError {
+			require.Error(t, err, "expected error for test %s", tc.name)
+		} else {
+			require.NoError(t, err, "failed to run test %s", tc.name)
+		}
+
+		statuses := map[string]string{}
+		for _, r := range o.Results {
+			statuses[r.Name] = r.Status
+		}
+		for name, status := range tc.expectStatuses {
+			assert.Equal(t, status, statuses[name], "status of check %s for test %s", name, tc.name)
+		}
+		assert.Contains(t, out.String(), "kubernetes version", "output for test %s", tc.name)
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"github.com/jenkins-x-plugins/jx-admin/pkg/cmd/create"
 	"github.com/jenkins-x-plugins/jx-admin/pkg/cmd/invitations"
 	"github.com/jenkins-x-plugins/jx-admin/pkg/cmd/joblog"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/cmd/joblog/archive"
 	"github.com/jenkins-x-plugins/jx-admin/pkg/cmd/operator"
 	"github.com/jenkins-x-plugins/jx-admin/pkg/cmd/plugins"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/cmd/preflight"
 	"github.com/jenkins-x-plugins/jx-admin/pkg/cmd/stop"
 	"github.com/jenkins-x-plugins/jx-admin/pkg/cmd/trigger"
 	"github.com/jenkins-x-plugins/jx-admin/pkg/cmd/version"
@@ -31,8 +33,11 @@ func Main() *cobra.Command {
 	}
 	cmd.AddCommand(cobras.SplitCommand(create.NewCmdCreate()))
 	cmd.AddCommand(cobras.SplitCommand(invitations.NewCmdInvitations()))
-	cmd.AddCommand(cobras.SplitCommand(joblog.NewCmdJobLog()))
+	logCmd := cobras.SplitCommand(joblog.NewCmdJobLog())
+	logCmd.AddCommand(cobras.SplitCommand(archive.NewCmdArchive()))
+	cmd.AddCommand(logCmd)
 	cmd.AddCommand(cobras.SplitCommand(operator.NewCmdOperator()))
+	cmd.AddCommand(cobras.SplitCommand(preflight.NewCmdPreflight()))
 	cmd.AddCommand(cobras.SplitCommand(stop.NewCmdJobStop()))
 	cmd.AddCommand(cobras.SplitCommand(trigger.NewCmdJobTrigger()))
 	cmd.AddCommand(cobras.SplitCommand(version.NewCmdVersion()))
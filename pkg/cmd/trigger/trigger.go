@@ -98,6 +98,7 @@ func (o *Options) Run() error {
 
 	if len(jobs) == 0 {
 		logger.Logger().Infof("there are no git operator jobs found in namespace %s", ns)
+		return nil
 	}
 
 	job := jobs[0]
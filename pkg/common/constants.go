@@ -4,6 +4,9 @@ const (
 	// DefaultOperatorNamespace the default namespace used to install the git operato
 	DefaultOperatorNamespace = "jx-git-operator"
 
+	// DefaultOperatorReleaseName the default helm release name of the git operator
+	DefaultOperatorReleaseName = "jxgo"
+
 	// DefaultBootRepository default git repo for boot with helm 3
 	DefaultBootRepository = "https://github.com/jx3-gitops-repositories/jx3-kubernetes.git"
 
@@ -3,10 +3,12 @@ package envfactory
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
 
 	"github.com/jenkins-x-plugins/jx-admin/pkg/common"
+	"github.com/jenkins-x-plugins/jx-admin/pkg/gitcreds"
 	"github.com/jenkins-x-plugins/jx-admin/pkg/reqhelpers"
 	"github.com/jenkins-x/go-scm/scm"
 	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
@@ -37,6 +39,7 @@ type EnvFactory struct {
 	OutDir               string
 	IOFileHandles        *files.IOFileHandles
 	ScmClient            *scm.Client
+	HTTPClient           *http.Client
 	BatchMode            bool
 	CreatedRepository    *scmhelpers.CreateRepository
 	CreatedScmRepository *scm.Repository
@@ -126,6 +129,7 @@ func (o *EnvFactory) CreateScmClient(gitServer, _, gitKind string) (*scm.Client,
 	if err != nil {
 		return scmClient, "", fmt.Errorf("failed to create SCM client for server %s: %w", gitServer, err)
 	}
+	gitcreds.UseHTTPClient(scmClient, o.HTTPClient)
 	return scmClient, o.ScmClientFactory.GitToken, nil
 }
 
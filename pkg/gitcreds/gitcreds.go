@@ -0,0 +1,195 @@
+package gitcreds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/go-scm/scm/factory"
+	"github.com/jenkins-x/go-scm/scm/transport"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/giturl"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/scmhelpers"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"golang.org/x/oauth2"
+)
+
+// NewScmClient creates a new SCM client for the git server of the given git repository URL using the username and token.
+// If a HTTP client is specified its transport is used to talk to the git server such as for a custom CA bundle or proxies
+func NewScmClient(httpClient *http.Client, gitURL, kind, username, token string) (*scm.Client, *giturl.GitRepository, error) {
+	repo, err := giturl.ParseGitURL(gitURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse git URL %s: %w", gitURL, err)
+	}
+	serverURL := repo.HostURLWithoutUser()
+	if IsSSHURL(gitURL) {
+		// lets use the HTTPS API of the git server
+		host, _, err := SSHHostAndPort(gitURL)
+		if err != nil {
+			return nil, repo, err
+		}
+		serverURL = "https://" + host
+	}
+	if kind == "" {
+		kind = giturl.SaasGitKind(serverURL)
+	}
+	if kind == "" {
+		return nil, repo, fmt.Errorf("could not detect the kind of git server %s so please specify the git kind", serverURL)
+	}
+	client, err := factory.NewClient(kind, serverURL, token, factory.SetUsername(username))
+	if err != nil {
+		return nil, repo, fmt.Errorf("failed to create SCM client for %s server %s: %w", kind, serverURL, err)
+	}
+	UseHTTPClient(client, httpClient)
+	return client, repo, nil
+}
+
+// UseHTTPClient configures the SCM client to use the transport of the given HTTP client while keeping any
+// authentication added by the SCM client
+func UseHTTPClient(client *scm.Client, httpClient *http.Client) {
+	if client == nil || httpClient == nil {
+		return
+	}
+	if client.Client == nil || client.Client.Transport == nil {
+		client.Client = httpClient
+		return
+	}
+	base := httpClient.Transport
+	switch t := client.Client.Transport.(type) {
+	case *oauth2.Transport:
+		t.Base = base
+	case *transport.Authorization:
+		t.Base = base
+	case *transport.BasicAuth:
+		t.Base = base
+	case *transport.BearerToken:
+		t.Base = base
+	case *transport.Custom:
+		t.Base = base
+	case *transport.PrivateToken:
+		t.Base = base
+	default:
+		log.Logger().Warnf("cannot configure the CA and proxies for the SCM client transport %T", t)
+	}
+}
+
+// VerifyUser verifies the token of the SCM client is valid and warns if it belongs to a different user
+func VerifyUser(ctx context.Context, client *scm.Client, username string) error {
+	user, res, err := client.Users.Find(ctx)
+	if err != nil {
+		if res != nil && res.Status == http.StatusUnauthorized {
+			return invalidTokenError(username, err)
+		}
+		return fmt.Errorf("failed to find the current git user for user %s: %w", username, err)
+	}
+	if user != nil && username != "" && user.Login != "" && !strings.EqualFold(user.Login, username) {
+		log.Logger().Warnf("the git token belongs to user %s rather than %s", user.Login, username)
+	}
+	return nil
+}
+
+// VerifyCredentials verifies the credentials can read the git repository so that we fail fast rather than the
+// boot Job failing to clone the repository inside the cluster. If there is no SCM client for the git server
+// then 'git ls-remote' is used
+func VerifyCredentials(ctx context.Context, client *scm.Client, runner cmdrunner.CommandRunner, gitURL, username, token string) error {
+	var err error
+	if client == nil {
+		err = VerifyLsRemote(runner, gitURL, username, token)
+	} else {
+		var repo *giturl.GitRepository
+		repo, err = giturl.ParseGitURL(gitURL)
+		if err != nil {
+			return fmt.Errorf("failed to parse git URL %s: %w", gitURL, err)
+		}
+		err = VerifyRepository(ctx, client, repo, username)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to verify the git credentials: %w", err)
+	}
+	log.Logger().Infof("verified user %s can read the git repository %s", termcolor.ColorInfo(username), termcolor.ColorInfo(gitURL))
+	return nil
+}
+
+// VerifyRepository verifies the repository exists and the token of the SCM client has read access to it
+func VerifyRepository(ctx context.Context, client *scm.Client, repo *giturl.GitRepository, username string) error {
+	fullName := scm.Join(repo.Organisation, repo.Name)
+	r, res, err := client.Repositories.Find(ctx, fullName)
+	if err != nil {
+		status := 0
+		if res != nil {
+			status = res.Status
+		}
+		switch status {
+		case http.StatusUnauthorized:
+			return invalidTokenError(username, err)
+		case http.StatusForbidden:
+			return noReadAccessError(username, fullName, err)
+		case http.StatusNotFound:
+			return notFoundError(username, fullName, err)
+		}
+		if scmhelpers.IsScmNotFound(err) {
+			return notFoundError(username, fullName, err)
+		}
+		return fmt.Errorf("failed to find git repository %s: %w", fullName, err)
+	}
+	if r != nil && r.Perm != nil && !r.Perm.Pull && !r.Perm.Push && !r.Perm.Admin {
+		return noReadAccessError(username, fullName, nil)
+	}
+	return nil
+}
+
+// VerifyLsRemote verifies the credentials can read the git repository by running 'git ls-remote'.
+// This is useful for git servers we cannot create an SCM client for
+func VerifyLsRemote(runner cmdrunner.CommandRunner, gitURL, username, token string) error {
+	u, err := url.Parse(gitURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse git URL %s: %w", gitURL, err)
+	}
+	u.User = url.UserPassword(username, token)
+	c := &cmdrunner.Command{
+		Name: "git",
+		Args: []string{"ls-remote", "--heads", u.String()},
+		Env: map[string]string{
+			"GIT_TERMINAL_PROMPT": "0",
+		},
+	}
+	text, err := runner(c)
+	if err == nil {
+		return nil
+	}
+
+	// lets make sure we don't include the token in any errors
+	message := strings.ReplaceAll(text+" "+err.Error(), token, "****")
+	err = errors.New(strings.TrimSpace(message))
+	lower := strings.ToLower(message)
+	switch {
+	case strings.Contains(lower, "authentication failed") || strings.Contains(lower, "401"):
+		return invalidTokenError(username, err)
+	case strings.Contains(lower, "403") || strings.Contains(lower, "permission denied"):
+		return noReadAccessError(username, gitURL, err)
+	case strings.Contains(lower, "not found") || strings.Contains(lower, "404"):
+		return notFoundError(username, gitURL, err)
+	}
+	return fmt.Errorf("failed to run git ls-remote on %s: %w", gitURL, err)
+}
+
+func invalidTokenError(username string, err error) error {
+	return fmt.Errorf("the git token for user %s is not valid (401). It may have expired or been revoked: %w", username, err)
+}
+
+func noReadAccessError(username, repository string, err error) error {
+	message := fmt.Sprintf("the git token for user %s does not have read access to repository %s (403). Make sure the token has the repo scope or read access to the repository", username, repository)
+	if err == nil {
+		return errors.New(message)
+	}
+	return fmt.Errorf("%s: %w", message, err)
+}
+
+func notFoundError(username, repository string, err error) error {
+	return fmt.Errorf("git repository %s does not exist or user %s cannot see it (404). If the repository is private make sure the token has the repo scope: %w", repository, username, err)
+}
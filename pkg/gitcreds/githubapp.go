@@ -0,0 +1,25 @@
+package gitcreds
+
+import (
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+)
+
+// RejectGitHubApp returns an error if any of the GitHub App options are specified. The git operator clones using
+// the url, username and password in its Secret and cannot mint its own GitHub App installation tokens. As
+// installation tokens expire after an hour the git operator would fail to clone the git repository an hour after
+// it was installed
+func RejectGitHubApp(appID, installationID, privateKeyFile string) error {
+	switch {
+	case appID != "":
+		return githubAppNotSupportedError("github-app-id", appID)
+	case installationID != "":
+		return githubAppNotSupportedError("github-app-installation-id", installationID)
+	case privateKeyFile != "":
+		return githubAppNotSupportedError("github-app-private-key", privateKeyFile)
+	}
+	return nil
+}
+
+func githubAppNotSupportedError(option, value string) error {
+	return options.InvalidOptionf(option, value, "GitHub App authentication is not supported yet as the git operator cannot mint its own installation tokens which expire after an hour. Please use the username and token of a bot user")
+}
@@ -0,0 +1,45 @@
+package gitcreds
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/giturl"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+)
+
+// IsSSHURL returns true if the git URL uses the SSH protocol such as git@github.com:myorg/myrepo.git or ssh://git@github.com/myorg/myrepo.git
+func IsSSHURL(gitURL string) bool {
+	return strings.HasPrefix(gitURL, "git@") || strings.HasPrefix(gitURL, "ssh://")
+}
+
+// SSHHostAndPort returns the host and optional port of the SSH git URL
+func SSHHostAndPort(gitURL string) (string, string, error) {
+	if strings.HasPrefix(gitURL, "ssh://") {
+		u, err := url.Parse(gitURL)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to parse git URL %s: %w", gitURL, err)
+		}
+		return u.Hostname(), u.Port(), nil
+	}
+	repo, err := giturl.ParseGitURL(gitURL)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse git URL %s: %w", gitURL, err)
+	}
+	host, port, err := net.SplitHostPort(repo.Host)
+	if err != nil {
+		return repo.Host, "", nil
+	}
+	return host, port, nil
+}
+
+// RejectSSHURL returns an error if the git URL uses the SSH protocol as the git operator can only clone using the
+// url, username and password in its Secret
+func RejectSSHURL(option, gitURL string) error {
+	if !IsSSHURL(gitURL) {
+		return nil
+	}
+	return options.InvalidOptionf(option, gitURL, "SSH git URLs are not supported yet as the git operator clones using the username and token in its Secret. Please use the https URL of the git repository")
+}
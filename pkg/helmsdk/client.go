@@ -0,0 +1,241 @@
+package helmsdk
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// Client implements Interface using the helm Go SDK
+type Client struct {
+	// Settings the helm environment settings such as the kube context and repository configuration
+	Settings *cli.EnvSettings
+}
+
+// NewClient creates a new client using the default helm environment settings
+func NewClient() *Client {
+	return &Client{
+		Settings: cli.New(),
+	}
+}
+
+// UpgradeInstall installs the release if it does not exist or upgrades it
+func (c *Client) UpgradeInstall(opts *ReleaseOptions) (*ReleaseStatus, error) {
+	cfg, err := c.configuration(opts.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	history := action.NewHistory(cfg)
+	history.Max = 1
+	_, err = history.Run(opts.ReleaseName)
+	install := false
+	if err != nil {
+		if !errors.Is(err, driver.ErrReleaseNotFound) {
+			return nil, fmt.Errorf("failed to find the history of release %s in namespace %s: %w", opts.ReleaseName, opts.Namespace, err)
+		}
+		install = true
+	}
+
+	var rel *release.Release
+	if install {
+		client := action.NewInstall(cfg)
+		client.ReleaseName = opts.ReleaseName
+		client.Namespace = opts.Namespace
+		client.CreateNamespace = opts.CreateNamespace
+		client.Version = opts.ChartVersion
+		client.RepoURL = opts.RepoURL
+
+		ch, vals, err := c.loadChart(&client.ChartPathOptions, opts)
+		if err != nil {
+			return nil, err
+		}
+		rel, err = client.Run(ch, vals)
+		if err != nil {
+			return nil, fmt.Errorf("failed to install release %s in namespace %s: %w", opts.ReleaseName, opts.Namespace, err)
+		}
+	} else {
+		client := action.NewUpgrade(cfg)
+		client.Namespace = opts.Namespace
+		client.Version = opts.ChartVersion
+		client.RepoURL = opts.RepoURL
+
+		ch, vals, err := c.loadChart(&client.ChartPathOptions, opts)
+		if err != nil {
+			return nil, err
+		}
+		rel, err = client.Run(opts.ReleaseName, ch, vals)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upgrade release %s in namespace %s: %w", opts.ReleaseName, opts.Namespace, err)
+		}
+	}
+	return ToReleaseStatus(rel), nil
+}
+
+// Template renders the chart returning the manifests. The chart is rendered locally unless the Validate option is
+// enabled in which case it is a dry run install against the cluster so that the capabilities of the cluster are used
+func (c *Client) Template(opts *ReleaseOptions) (string, error) {
+	var cfg *action.Configuration
+	if opts.Validate {
+		var err error
+		cfg, err = c.configuration(opts.Namespace)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		registryClient, err := c.registryClient()
+		if err != nil {
+			return "", err
+		}
+		cfg = &action.Configuration{
+			RegistryClient: registryClient,
+		}
+	}
+	client := action.NewInstall(cfg)
+	client.DryRun = true
+	client.ClientOnly = !opts.Validate
+	client.IsUpgrade = opts.IsUpgrade
+	client.Replace = true
+	client.IncludeCRDs = true
+	client.ReleaseName = opts.ReleaseName
+	client.Namespace = opts.Namespace
+	client.Version = opts.ChartVersion
+	client.RepoURL = opts.RepoURL
+
+	ch, vals, err := c.loadChart(&client.ChartPathOptions, opts)
+	if err != nil {
+		return "", err
+	}
+	rel, err := client.Run(ch, vals)
+	if err != nil {
+		return "", fmt.Errorf("failed to render chart %s: %w", opts.ChartName, err)
+	}
+	return rel.Manifest, nil
+}
+
+// GetRelease returns the deployed release or nil if it does not exist
+func (c *Client) GetRelease(releaseName, namespace string) (*Release, error) {
+	cfg, err := c.configuration(namespace)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := action.NewGet(cfg).Run(releaseName)
+	if err != nil {
+		if errors.Is(err, driver.ErrReleaseNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get release %s in namespace %s: %w", releaseName, namespace, err)
+	}
+	answer := &Release{
+		Manifest: rel.Manifest,
+		Values:   rel.Config,
+	}
+	status := ToReleaseStatus(rel)
+	if status != nil {
+		answer.ReleaseStatus = *status
+	}
+	return answer, nil
+}
+
+// MergeValues merges the values files and set values in the same way as 'helm install --values --set'
+func MergeValues(valueFiles, setValues []string) (map[string]interface{}, error) {
+	valueOptions := &values.Options{
+		ValueFiles: valueFiles,
+		Values:     setValues,
+	}
+	vals, err := valueOptions.MergeValues(getter.All(cli.New()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge the chart values: %w", err)
+	}
+	return vals, nil
+}
+
+// configuration creates the helm action configuration for the given namespace
+func (c *Client) configuration(ns string) (*action.Configuration, error) {
+	if c.Settings == nil {
+		c.Settings = cli.New()
+	}
+	if ns != "" {
+		c.Settings.SetNamespace(ns)
+	}
+	cfg := &action.Configuration{}
+	err := cfg.Init(c.Settings.RESTClientGetter(), c.Settings.Namespace(), os.Getenv("HELM_DRIVER"), log.Logger().Debugf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create helm configuration for namespace %s: %w", ns, err)
+	}
+	cfg.RegistryClient, err = c.registryClient()
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// registryClient creates the client used to pull charts from OCI registries using the helm registry credentials
+func (c *Client) registryClient() (*registry.Client, error) {
+	if c.Settings == nil {
+		c.Settings = cli.New()
+	}
+	client, err := registry.NewClient(
+		registry.ClientOptCredentialsFile(c.Settings.RegistryConfig),
+		registry.ClientOptWriter(os.Stderr),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create helm registry client: %w", err)
+	}
+	return client, nil
+}
+
+// loadChart locates, downloads if required and loads the chart along with the values to use
+func (c *Client) loadChart(chartPathOptions *action.ChartPathOptions, opts *ReleaseOptions) (*chart.Chart, map[string]interface{}, error) {
+	chartPathOptions.Verify = opts.Verify
+	chartPathOptions.Keyring = opts.Keyring
+	chartPath, err := chartPathOptions.LocateChart(opts.ChartName, c.Settings)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to locate chart %s: %w", opts.ChartName, err)
+	}
+	ch, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load chart %s: %w", chartPath, err)
+	}
+
+	vals, err := MergeValues(opts.ValueFiles, opts.SetValues)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ch, vals, nil
+}
+
+// ToReleaseStatus converts the helm release into a status
+func ToReleaseStatus(rel *release.Release) *ReleaseStatus {
+	if rel == nil {
+		return nil
+	}
+	answer := &ReleaseStatus{
+		Name:      rel.Name,
+		Namespace: rel.Namespace,
+		Revision:  rel.Version,
+	}
+	if rel.Chart != nil && rel.Chart.Metadata != nil {
+		answer.Chart = rel.Chart.Metadata.Name
+		answer.ChartVersion = rel.Chart.Metadata.Version
+		answer.AppVersion = rel.Chart.Metadata.AppVersion
+	}
+	if rel.Info != nil {
+		answer.Status = rel.Info.Status.String()
+		answer.Description = rel.Info.Description
+		answer.LastDeployed = rel.Info.LastDeployed.Time
+	}
+	return answer
+}
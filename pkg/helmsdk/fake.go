@@ -0,0 +1,76 @@
+package helmsdk
+
+import (
+	"time"
+)
+
+// FakeClient a fake implementation of Interface for testing
+type FakeClient struct {
+	// Installs the options of each UpgradeInstall invocation in order
+	Installs []*ReleaseOptions
+
+	// Templates the options of each Template invocation in order
+	Templates []*ReleaseOptions
+
+	// Manifest the manifest returned by Template
+	Manifest string
+
+	// Releases the releases indexed by namespace and release name
+	Releases map[string]*ReleaseStatus
+
+	// Deployed the deployed releases returned by GetRelease indexed by namespace and release name
+	Deployed map[string]*Release
+}
+
+// NewFakeClient creates a new fake client
+func NewFakeClient() *FakeClient {
+	return &FakeClient{
+		Releases: map[string]*ReleaseStatus{},
+	}
+}
+
+// UpgradeInstall fakes installing or upgrading a release
+func (f *FakeClient) UpgradeInstall(opts *ReleaseOptions) (*ReleaseStatus, error) {
+	f.Installs = append(f.Installs, opts)
+	if f.Releases == nil {
+		f.Releases = map[string]*ReleaseStatus{}
+	}
+	key := releaseKey(opts.Namespace, opts.ReleaseName)
+	rel := f.Releases[key]
+	if rel == nil {
+		rel = &ReleaseStatus{
+			Name:      opts.ReleaseName,
+			Namespace: opts.Namespace,
+		}
+		f.Releases[key] = rel
+	}
+	rel.Revision++
+	rel.Chart = opts.ChartName
+	rel.ChartVersion = opts.ChartVersion
+	rel.Status = "deployed"
+	rel.LastDeployed = time.Now()
+	return rel, nil
+}
+
+// Template fakes rendering the chart
+func (f *FakeClient) Template(opts *ReleaseOptions) (string, error) {
+	f.Templates = append(f.Templates, opts)
+	return f.Manifest, nil
+}
+
+// GetRelease fakes returning the deployed release
+func (f *FakeClient) GetRelease(releaseName, namespace string) (*Release, error) {
+	return f.Deployed[releaseKey(namespace, releaseName)], nil
+}
+
+// AddDeployed adds a deployed release to be returned by GetRelease
+func (f *FakeClient) AddDeployed(rel *Release) {
+	if f.Deployed == nil {
+		f.Deployed = map[string]*Release{}
+	}
+	f.Deployed[releaseKey(rel.Namespace, rel.Name)] = rel
+}
+
+func releaseKey(ns, name string) string {
+	return ns + "/" + name
+}
@@ -0,0 +1,81 @@
+package helmsdk
+
+import (
+	"time"
+)
+
+// Interface performs helm release operations in process using the helm Go SDK
+type Interface interface {
+	// UpgradeInstall installs the release if it does not exist or upgrades it
+	UpgradeInstall(opts *ReleaseOptions) (*ReleaseStatus, error)
+
+	// Template renders the chart returning the manifests. The chart is rendered locally unless the Validate option is enabled
+	Template(opts *ReleaseOptions) (string, error)
+
+	// GetRelease returns the deployed release or nil if it does not exist
+	GetRelease(releaseName, namespace string) (*Release, error)
+}
+
+// ReleaseOptions the options to install or upgrade a release
+type ReleaseOptions struct {
+	// ReleaseName the name of the release
+	ReleaseName string
+
+	// Namespace the namespace to install the release into
+	Namespace string
+
+	// ChartName the name of the chart such as 'jxgh/jx-git-operator', a local chart or an 'oci://' reference
+	ChartName string
+
+	// ChartVersion the optional version of the chart
+	ChartVersion string
+
+	// RepoURL the optional chart repository URL if the chart name is not a local chart or in a local helm repository
+	RepoURL string
+
+	// ValueFiles the values files in the same format as 'helm install --values'
+	ValueFiles []string
+
+	// SetValues the values to set in the same format as 'helm install --set'
+	SetValues []string
+
+	// Verify verifies the provenance file of the chart before using it
+	Verify bool
+
+	// Keyring the optional keyring used to verify the provenance file of the chart
+	Keyring string
+
+	// CreateNamespace creates the namespace if it does not exist
+	CreateNamespace bool
+
+	// Validate renders the chart against the cluster using its API versions and capabilities in the same way as
+	// 'helm template --validate' rather than client side
+	Validate bool
+
+	// IsUpgrade renders the chart as an upgrade of the deployed release in the same way as 'helm template --is-upgrade'
+	IsUpgrade bool
+}
+
+// Release the deployed release along with its manifest and the user supplied values
+type Release struct {
+	ReleaseStatus
+
+	// Manifest the rendered manifest of the release
+	Manifest string
+
+	// Values the values supplied by the user when installing or upgrading the release
+	Values map[string]interface{}
+}
+
+// ReleaseStatus the status of a release
+type ReleaseStatus struct {
+	Name         string    `json:"name"`
+	Namespace    string    `json:"namespace"`
+	Revision     int       `json:"revision"`
+	Chart        string    `json:"chart"`
+	ChartVersion string    `json:"chartVersion"`
+	AppVersion   string    `json:"appVersion,omitempty"`
+	Status       string    `json:"status"`
+	Description  string    `json:"description,omitempty"`
+	LastDeployed time.Time `json:"lastDeployed,omitempty"`
+}
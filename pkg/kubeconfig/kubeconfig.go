@@ -0,0 +1,225 @@
+package kubeconfig
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube"
+	"github.com/jenkins-x/jx-kube-client/v3/pkg/kubeclient"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// ContextPrefix the prefix of the dedicated kube context created for the git operator namespace of a cluster
+const ContextPrefix = "jx-"
+
+var invalidContextChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// Options the kube config file and context used to connect to the cluster
+type Options struct {
+	File    string
+	Context string
+}
+
+// AddFlags adds the CLI flags for the kube config file and context
+func (o *Options) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&o.File, "kubeconfig", "", "", "the kube config file used to connect to the cluster. If not specified defaults to $KUBECONFIG or ~/.kube/config")
+	cmd.Flags().StringVarP(&o.Context, "context", "", "", "the kube context used to connect to the cluster. If not specified the current context is used")
+}
+
+// IsEmpty returns true if neither the kube config file or context have been specified
+func (o *Options) IsEmpty() bool {
+	return o.File == "" && o.Context == ""
+}
+
+// HelmArgs returns the helm command line arguments to use the kube config file and context
+func (o *Options) HelmArgs() []string {
+	var answer []string
+	if o.Context != "" {
+		answer = append(answer, "--kube-context", o.Context)
+	}
+	if o.File != "" {
+		answer = append(answer, "--kubeconfig", o.File)
+	}
+	return answer
+}
+
+// LazyCreateKubeClient lazily creates the kubernetes client using the kube config file and context
+func (o *Options) LazyCreateKubeClient(client kubernetes.Interface) (kubernetes.Interface, error) {
+	if client != nil {
+		return client, nil
+	}
+	if o.IsEmpty() {
+		return kube.LazyCreateKubeClientWithMandatory(client, true)
+	}
+	cfg, err := o.RESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, err = kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error building kubernetes clientset: %w", err)
+	}
+	return client, nil
+}
+
+// LazyCreateDynamicClient lazily creates the dynamic client using the kube config file and context
+func (o *Options) LazyCreateDynamicClient(client dynamic.Interface) (dynamic.Interface, error) {
+	if client != nil {
+		return client, nil
+	}
+	if o.IsEmpty() {
+		return kube.LazyCreateDynamicClient(client)
+	}
+	cfg, err := o.RESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, err = dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error building dynamic client: %w", err)
+	}
+	return client, nil
+}
+
+// RESTConfig returns the REST configuration of the kube config file and context
+func (o *Options) RESTConfig() (*rest.Config, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = o.File
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: o.Context}
+	cfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the kube config for context %s: %w", o.Context, err)
+	}
+	return cfg, nil
+}
+
+// LoadConfig loads the kube config file
+func (o *Options) LoadConfig() (*api.Config, *clientcmd.PathOptions, error) {
+	if o.File == "" {
+		return kubeclient.LoadConfig()
+	}
+	po := clientcmd.NewDefaultPathOptions()
+	po.LoadingRules.ExplicitPath = o.File
+	config, err := po.GetStartingConfig()
+	if err != nil {
+		return nil, po, fmt.Errorf("could not load the kube config file %s: %w", o.File, err)
+	}
+	return config, po, nil
+}
+
+// ContextName returns the name of the context to use in the config
+func (o *Options) ContextName(config *api.Config) string {
+	if o.Context != "" {
+		return o.Context
+	}
+	if config == nil {
+		return ""
+	}
+	return config.CurrentContext
+}
+
+// SelectedContext returns the context to use in the config or nil if there is none
+func (o *Options) SelectedContext(config *api.Config) (*api.Context, error) {
+	name := o.ContextName(config)
+	if name == "" || config == nil || config.Contexts == nil {
+		return nil, nil
+	}
+	ctx := config.Contexts[name]
+	if ctx == nil && o.Context != "" {
+		return nil, fmt.Errorf("there is no context %s in the kube config", o.Context)
+	}
+	return ctx, nil
+}
+
+// Server returns the name of the context and the server URL of its cluster. Returns empty values if
+// there is no kube config such as when running inside a pod
+func (o *Options) Server() (string, string, error) {
+	config, _, err := o.LoadConfig()
+	if err != nil {
+		return "", "", err
+	}
+	ctx, err := o.SelectedContext(config)
+	if err != nil || ctx == nil {
+		return "", "", err
+	}
+	return o.ContextName(config), kube.Server(config, ctx), nil
+}
+
+// CurrentNamespace returns the namespace of the context or the current namespace if no context is specified
+func (o *Options) CurrentNamespace() (string, error) {
+	if o.IsEmpty() {
+		return kubeclient.CurrentNamespace()
+	}
+	config, _, err := o.LoadConfig()
+	if err != nil {
+		return "", err
+	}
+	ctx, err := o.SelectedContext(config)
+	if err != nil {
+		return "", err
+	}
+	if ctx != nil && ctx.Namespace != "" {
+		return ctx.Namespace, nil
+	}
+	return "default", nil
+}
+
+// SwitchNamespace changes the namespace of the context
+func (o *Options) SwitchNamespace(ns string) (bool, error) {
+	config, po, err := o.LoadConfig()
+	if err != nil {
+		return false, fmt.Errorf("loading Kubernetes configuration: %w", err)
+	}
+	ctx, err := o.SelectedContext(config)
+	if err != nil || ctx == nil {
+		return false, err
+	}
+	if ctx.Namespace == ns {
+		return true, nil
+	}
+	ctx.Namespace = ns
+	err = clientcmd.ModifyConfig(po, *config, false)
+	if err != nil {
+		return false, fmt.Errorf("failed to update the kube config to namespace %s: %w", ns, err)
+	}
+	return true, nil
+}
+
+// CreateContext creates or updates a dedicated context called 'jx-<cluster>' for the cluster and user of the
+// context which uses the given namespace then makes it the current context. The existing context is not modified.
+// Returns the name of the context or an empty string if there is no context to copy
+func (o *Options) CreateContext(ns string) (string, error) {
+	config, po, err := o.LoadConfig()
+	if err != nil {
+		return "", fmt.Errorf("loading Kubernetes configuration: %w", err)
+	}
+	ctx, err := o.SelectedContext(config)
+	if err != nil || ctx == nil {
+		return "", err
+	}
+	name := NewContextName(ctx.Cluster)
+	newCtx := ctx.DeepCopy()
+	newCtx.Namespace = ns
+	config.Contexts[name] = newCtx
+	config.CurrentContext = name
+	err = clientcmd.ModifyConfig(po, *config, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to create the kube context %s: %w", name, err)
+	}
+	return name, nil
+}
+
+// NewContextName returns the name of the dedicated context for the cluster
+func NewContextName(cluster string) string {
+	name := strings.Trim(invalidContextChars.ReplaceAllString(cluster, "-"), "-")
+	if name == "" {
+		name = "cluster"
+	}
+	return ContextPrefix + name
+}
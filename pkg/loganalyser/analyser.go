@@ -0,0 +1,346 @@
+package loganalyser
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// PhaseGitClone cloning the git repositories such as the version stream
+	PhaseGitClone = "git clone"
+
+	// PhaseSecrets populating the secrets
+	PhaseSecrets = "secret population"
+
+	// PhaseTemplate generating the kubernetes resources from the helmfile releases
+	PhaseTemplate = "helmfile template"
+
+	// PhaseApply applying the kubernetes resources and helmfile releases to the cluster
+	PhaseApply = "apply"
+
+	// PhaseVerify verifying the installation
+	PhaseVerify = "verification"
+
+	// PhaseSetup any output before the first phase is recognised
+	PhaseSetup = "setup"
+)
+
+const (
+	// StatusSucceeded the phase or release succeeded
+	StatusSucceeded = "Succeeded"
+
+	// StatusFailed the phase or release failed
+	StatusFailed = "Failed"
+
+	// StatusRunning the phase or release is still running
+	StatusRunning = "Running"
+)
+
+// DefaultMaxErrorLines the default maximum number of lines in the error excerpt
+const DefaultMaxErrorLines = 20
+
+// contextLines the number of lines before an error line included in the error excerpt
+const contextLines = 2
+
+type phaseMatcher struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+var (
+	// phaseMatchers the patterns of the lines which start each phase
+	phaseMatchers = []phaseMatcher{
+		{name: PhaseGitClone, pattern: regexp.MustCompile(`\bgit clone\b|\bjx gitops git clone\b|^Cloning into `)},
+		{name: PhaseSecrets, pattern: regexp.MustCompile(`\bjx secret (populate|wait|verify|convert)\b|\bjx gitops secret\b`)},
+		{name: PhaseTemplate, pattern: regexp.MustCompile(`\bhelmfile\b.*\btemplate\b|\bjx gitops helmfile (resolve|template|move|structure)\b`)},
+		{name: PhaseApply, pattern: regexp.MustCompile(`\bkubectl apply\b|\bhelmfile\b.*\b(apply|sync)\b|\bjx gitops (apply|helmfile apply)\b`)},
+		{name: PhaseVerify, pattern: regexp.MustCompile(`\bjx (verify|health)\b|\bjx gitops (verify|postprocess)\b|\bjx admin verify\b`)},
+	}
+
+	releaseStartPattern     = regexp.MustCompile(`\b(?:Templating|Upgrading|Installing|Building dependency|Adding repo|Deleting) release=([\w.-]+)`)
+	releaseSucceededPattern = regexp.MustCompile(`^Release "([\w.-]+)" has been upgraded|^Release "([\w.-]+)" does not exist\. Installing it now|^NAME: ([\w.-]+)$`)
+	releaseFailedPattern    = regexp.MustCompile(`(?:release|Release) "?([\w.-]+)"? failed|failed processing release ([\w.-]+)|\bin \S*helmfile\.yaml: .*release "([\w.-]+)"`)
+	failedReleasesPattern   = regexp.MustCompile(`^FAILED RELEASES:`)
+	errorPattern            = regexp.MustCompile(`(?i)^(error|fatal)\b|\berror:|\bUPGRADE FAILED\b|\bFAILED RELEASES:|^make(\[\d+\])?: \*\*\*|\blevel=(error|fatal)\b|\bERR\b|\bpanic:`)
+	failedMakePattern       = regexp.MustCompile(`^make(\[\d+\])?: \*\*\* .*Error \d+`)
+)
+
+// Release the status of a helmfile release
+type Release struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// Phase the status of a phase of the boot Job
+type Phase struct {
+	Name     string        `json:"name"`
+	Status   string        `json:"status"`
+	Start    time.Time     `json:"start,omitempty"`
+	End      time.Time     `json:"end,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+	Lines    int           `json:"lines"`
+	Releases []*Release    `json:"releases,omitempty"`
+}
+
+// Summary the summary of a boot Job log
+type Summary struct {
+	Status        string   `json:"status"`
+	Phases        []*Phase `json:"phases"`
+	FailedPhase   string   `json:"failedPhase,omitempty"`
+	FailedRelease string   `json:"failedRelease,omitempty"`
+	ErrorExcerpt  []string `json:"errorExcerpt,omitempty"`
+}
+
+// Analyser analyses the lines of a boot Job log to recognise the boot phases and any errors
+type Analyser struct {
+	// MaxErrorLines the maximum number of lines in the error excerpt
+	MaxErrorLines int
+
+	phases          []*Phase
+	current         *Phase
+	release         *Release
+	failedRelease   string
+	inFailedList    bool
+	makeFailed      bool
+	recent          []string
+	tail            []string
+	errorLines      []string
+	firstErrorLines int
+	lastErrorLineNo int
+	lineNo          int
+	lastTime        time.Time
+}
+
+// NewAnalyser creates a new log analyser
+func NewAnalyser() *Analyser {
+	return &Analyser{
+		MaxErrorLines: DefaultMaxErrorLines,
+	}
+}
+
+// AddLine analyses the next line of the log. The time is optional and is used to calculate the phase durations
+func (a *Analyser) AddLine(t time.Time, line string) {
+	a.lineNo++
+	if !t.IsZero() {
+		a.lastTime = t
+	}
+	text := strings.TrimSpace(line)
+
+	for _, m := range phaseMatchers {
+		if m.pattern.MatchString(text) {
+			a.startPhase(m.name, t)
+			break
+		}
+	}
+	if a.current == nil {
+		a.startPhase(PhaseSetup, t)
+	}
+	a.current.Lines++
+	if !t.IsZero() {
+		a.current.End = t
+	}
+
+	a.analyseRelease(text)
+	a.analyseErrors(line, text)
+}
+
+// Summary returns the summary of the log analysed so far. The job status is the status of the boot Job or pod
+// which is used to determine if the last phase failed or is still running. If the job status is empty it is
+// derived from the log
+func (a *Analyser) Summary(jobStatus string) *Summary {
+	if jobStatus == "" {
+		jobStatus = StatusRunning
+		if a.makeFailed {
+			jobStatus = StatusFailed
+		}
+	}
+	answer := &Summary{
+		Status: jobStatus,
+		Phases: a.phases,
+	}
+	for i, p := range a.phases {
+		if !p.Start.IsZero() && !p.End.IsZero() {
+			p.Duration = p.End.Sub(p.Start).Round(time.Second)
+		}
+		last := i == len(a.phases)-1
+		switch {
+		case !last:
+			p.Status = StatusSucceeded
+		case jobStatus == StatusFailed:
+			p.Status = StatusFailed
+		default:
+			p.Status = jobStatus
+		}
+		for _, r := range p.Releases {
+			if r.Status == StatusRunning {
+				if last && jobStatus == StatusFailed && r == a.release && a.failedRelease == "" {
+					r.Status = StatusFailed
+				} else if !last || jobStatus == StatusSucceeded {
+					r.Status = StatusSucceeded
+				}
+			}
+		}
+	}
+	if jobStatus != StatusFailed {
+		return answer
+	}
+
+	if len(a.phases) > 0 {
+		answer.FailedPhase = a.phases[len(a.phases)-1].Name
+	}
+	answer.FailedRelease = a.failedRelease
+	if answer.FailedRelease == "" && a.release != nil && a.release.Status == StatusFailed {
+		answer.FailedRelease = a.release.Name
+	}
+	answer.ErrorExcerpt = a.errorLines
+	if len(answer.ErrorExcerpt) == 0 {
+		// lets show the end of the log if we could not find any errors
+		answer.ErrorExcerpt = a.tail
+	}
+	return answer
+}
+
+func (a *Analyser) startPhase(name string, t time.Time) {
+	if a.current != nil {
+		if a.current.Name == name {
+			return
+		}
+		if !t.IsZero() {
+			a.current.End = t
+		}
+	}
+	a.current = &Phase{
+		Name:   name,
+		Status: StatusRunning,
+		Start:  t,
+		End:    t,
+	}
+	a.phases = append(a.phases, a.current)
+	a.release = nil
+}
+
+func (a *Analyser) analyseRelease(text string) {
+	if failedReleasesPattern.MatchString(text) {
+		a.inFailedList = true
+		return
+	}
+	if a.inFailedList {
+		// helmfile lists the failed releases after the FAILED RELEASES: line
+		name := strings.Fields(text)
+		if len(name) == 1 && name[0] != "NAME" {
+			a.markFailed(name[0])
+			return
+		}
+		if len(name) == 0 || name[0] != "NAME" {
+			a.inFailedList = false
+		}
+	}
+	if m := releaseFailedPattern.FindStringSubmatch(text); m != nil {
+		a.markFailed(firstGroup(m))
+		return
+	}
+	if m := releaseStartPattern.FindStringSubmatch(text); m != nil {
+		a.release = a.findOrAddRelease(m[1])
+		return
+	}
+	if m := releaseSucceededPattern.FindStringSubmatch(text); m != nil {
+		r := a.findOrAddRelease(firstGroup(m))
+		if r.Status != StatusFailed {
+			r.Status = StatusSucceeded
+		}
+		return
+	}
+	if strings.Contains(text, "UPGRADE FAILED") && a.release != nil {
+		a.markFailed(a.release.Name)
+	}
+}
+
+func (a *Analyser) markFailed(name string) {
+	if name == "" {
+		return
+	}
+	r := a.findOrAddRelease(name)
+	r.Status = StatusFailed
+	a.release = r
+	if a.failedRelease == "" {
+		a.failedRelease = name
+	}
+}
+
+func (a *Analyser) findOrAddRelease(name string) *Release {
+	for _, r := range a.current.Releases {
+		if r.Name == name {
+			return r
+		}
+	}
+	r := &Release{
+		Name:   name,
+		Status: StatusRunning,
+	}
+	a.current.Releases = append(a.current.Releases, r)
+	return r
+}
+
+func (a *Analyser) analyseErrors(line, text string) {
+	a.tail = append(a.tail, line)
+	if a.MaxErrorLines > 0 && len(a.tail) > a.MaxErrorLines {
+		a.tail = a.tail[len(a.tail)-a.MaxErrorLines:]
+	}
+	if failedMakePattern.MatchString(text) {
+		a.makeFailed = true
+	}
+	if errorPattern.MatchString(text) {
+		if a.lineNo-a.lastErrorLineNo > 1 {
+			// lets include the lines before the error for context
+			if len(a.errorLines) > 0 {
+				if a.firstErrorLines == 0 {
+					a.firstErrorLines = len(a.errorLines)
+				}
+				a.errorLines = append(a.errorLines, "...")
+			}
+			a.errorLines = append(a.errorLines, a.recent...)
+		}
+		a.errorLines = append(a.errorLines, line)
+		a.lastErrorLineNo = a.lineNo
+		a.trimErrorLines()
+		a.recent = nil
+		return
+	}
+	a.recent = append(a.recent, line)
+	if len(a.recent) > contextLines {
+		a.recent = a.recent[len(a.recent)-contextLines:]
+	}
+}
+
+// trimErrorLines trims the error excerpt to the maximum number of lines. The first error block is kept as it
+// usually contains the root cause along with as many of the most recent error lines as fit
+func (a *Analyser) trimErrorLines() {
+	maxLines := a.MaxErrorLines
+	if maxLines <= 0 || len(a.errorLines) <= maxLines {
+		return
+	}
+	first := a.firstErrorLines
+	if first == 0 || first >= maxLines-1 {
+		// the first error block fills the excerpt
+		a.errorLines = a.errorLines[:maxLines]
+		return
+	}
+	rest := a.errorLines[len(a.errorLines)-(maxLines-first-1):]
+	if rest[0] == "..." {
+		rest = rest[1:]
+	}
+	answer := make([]string, 0, maxLines)
+	answer = append(answer, a.errorLines[:first]...)
+	answer = append(answer, "...")
+	a.errorLines = append(answer, rest...)
+}
+
+func firstGroup(m []string) string {
+	for _, s := range m[1:] {
+		if s != "" {
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,119 @@
+package loganalyser_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/loganalyser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyserFailedBoot(t *testing.T) {
+	a := analyseFile(t, "failed.log")
+
+	summary := a.Summary(loganalyser.StatusFailed)
+	assert.Equal(t, []string{
+		loganalyser.PhaseSetup,
+		loganalyser.PhaseGitClone,
+		loganalyser.PhaseTemplate,
+		loganalyser.PhaseSecrets,
+		loganalyser.PhaseApply,
+	}, phaseNames(summary), "phases")
+
+	assert.Equal(t, loganalyser.PhaseApply, summary.FailedPhase, "failed phase")
+	assert.Equal(t, "nginx-ingress", summary.FailedRelease, "failed release")
+	assert.Contains(t, summary.ErrorExcerpt, "Error: UPGRADE FAILED: timed out waiting for the condition", "error excerpt")
+	assert.Contains(t, summary.ErrorExcerpt, "waiting for the load balancer", "error excerpt context")
+
+	apply := summary.Phases[len(summary.Phases)-1]
+	assert.Equal(t, loganalyser.StatusFailed, apply.Status, "apply status")
+	assert.Equal(t, 13*time.Second, apply.Duration, "apply duration")
+	require.Len(t, apply.Releases, 2, "apply releases")
+	assert.Equal(t, "jx-git-operator", apply.Releases[0].Name, "first release")
+	assert.Equal(t, loganalyser.StatusSucceeded, apply.Releases[0].Status, "first release status")
+	assert.Equal(t, "nginx-ingress", apply.Releases[1].Name, "second release")
+	assert.Equal(t, loganalyser.StatusFailed, apply.Releases[1].Status, "second release status")
+
+	for _, p := range summary.Phases[:len(summary.Phases)-1] {
+		assert.Equal(t, loganalyser.StatusSucceeded, p.Status, "status of phase %s", p.Name)
+	}
+
+	// the failure is detected from the make error if the job status is not known
+	assert.Equal(t, loganalyser.StatusFailed, a.Summary("").Status, "derived status")
+}
+
+func TestAnalyserSucceededBoot(t *testing.T) {
+	a := analyseFile(t, "succeeded.log")
+
+	summary := a.Summary(loganalyser.StatusSucceeded)
+	assert.Equal(t, []string{
+		loganalyser.PhaseGitClone,
+		loganalyser.PhaseSecrets,
+		loganalyser.PhaseTemplate,
+		loganalyser.PhaseApply,
+		loganalyser.PhaseVerify,
+	}, phaseNames(summary), "phases")
+	for _, p := range summary.Phases {
+		assert.Equal(t, loganalyser.StatusSucceeded, p.Status, "status of phase %s", p.Name)
+	}
+	assert.Empty(t, summary.FailedPhase, "failed phase")
+	assert.Empty(t, summary.ErrorExcerpt, "error excerpt")
+
+	running := a.Summary("")
+	assert.Equal(t, loganalyser.StatusRunning, running.Status, "derived status")
+}
+
+func TestAnalyserErrorExcerptKeepsFirstError(t *testing.T) {
+	a := loganalyser.NewAnalyser()
+	a.MaxErrorLines = 8
+	a.AddLine(time.Time{}, "cloning the repository")
+	a.AddLine(time.Time{}, "Error: failed to resolve the version stream")
+	for i := 1; i <= 10; i++ {
+		a.AddLine(time.Time{}, fmt.Sprintf("retrying %d", i))
+		a.AddLine(time.Time{}, fmt.Sprintf("error: retry %d failed", i))
+	}
+
+	excerpt := a.Summary(loganalyser.StatusFailed).ErrorExcerpt
+	t.Logf("excerpt:\n%s\n", strings.Join(excerpt, "\n"))
+	assert.Len(t, excerpt, a.MaxErrorLines, "error excerpt")
+	assert.Equal(t, []string{"cloning the repository", "Error: failed to resolve the version stream", "..."}, excerpt[:3], "first error block")
+	assert.Equal(t, "error: retry 10 failed", excerpt[len(excerpt)-1], "last error")
+}
+
+func TestAnalyserErrorExcerptWithoutErrors(t *testing.T) {
+	a := loganalyser.NewAnalyser()
+	for i := 1; i <= 30; i++ {
+		a.AddLine(time.Time{}, fmt.Sprintf("line %d", i))
+	}
+
+	excerpt := a.Summary(loganalyser.StatusFailed).ErrorExcerpt
+	require.Len(t, excerpt, loganalyser.DefaultMaxErrorLines, "error excerpt")
+	assert.Equal(t, "line 11", excerpt[0], "first line of the excerpt")
+	assert.Equal(t, "line 30", excerpt[len(excerpt)-1], "last line of the excerpt")
+}
+
+// analyseFile analyses the test log file with each line one second apart
+func analyseFile(t *testing.T, name string) *loganalyser.Analyser {
+	data, err := os.ReadFile(filepath.Join("test_data", name))
+	require.NoError(t, err, "failed to load %s", name)
+
+	a := loganalyser.NewAnalyser()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		a.AddLine(start.Add(time.Duration(i)*time.Second), line)
+	}
+	return a
+}
+
+func phaseNames(summary *loganalyser.Summary) []string {
+	var answer []string
+	for _, p := range summary.Phases {
+		answer = append(answer, p.Name)
+	}
+	return answer
+}
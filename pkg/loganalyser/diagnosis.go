@@ -0,0 +1,247 @@
+package loganalyser
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Rule a known failure signature in the boot log or kubernetes events with its remedy
+type Rule struct {
+	// Name the unique name of the rule. A user supplied rule replaces a built-in rule with the same name
+	Name string `json:"name"`
+
+	// Description describes the failure
+	Description string `json:"description"`
+
+	// LogPatterns the regular expressions matched against each line of the boot log
+	LogPatterns []string `json:"logPatterns,omitempty"`
+
+	// EventPatterns the regular expressions matched against the 'reason: message' of the events of the boot Job and its pods
+	EventPatterns []string `json:"eventPatterns,omitempty"`
+
+	// Fix the suggested fix
+	Fix string `json:"fix"`
+
+	// DocURL the documentation to read for more help
+	DocURL string `json:"docURL,omitempty"`
+
+	logRegexps   []*regexp.Regexp
+	eventRegexps []*regexp.Regexp
+}
+
+// RuleSet the format of a user supplied rules file
+type RuleSet struct {
+	Rules []*Rule `json:"rules"`
+}
+
+// Diagnosis a matched rule with the log line or event which matched
+type Diagnosis struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Fix         string `json:"fix"`
+	DocURL      string `json:"docURL,omitempty"`
+	Evidence    string `json:"evidence"`
+}
+
+// DefaultRules returns the built-in rules for the common boot failures
+func DefaultRules() []*Rule {
+	return []*Rule{
+		{
+			Name:        "git-token-expired",
+			Description: "the git operator could not authenticate with the git provider",
+			LogPatterns: []string{
+				`(?i)authentication failed for`,
+				`(?i)invalid username or password`,
+				`(?i)bad credentials`,
+				`(?i)could not read username for`,
+				`(?i)\b401 unauthorized\b`,
+			},
+			Fix:    "the git token may have expired or been revoked. Create a new token then update the git operator with: jx admin operator rotate-credentials",
+			DocURL: "https://jayex.io/v3/admin/setup/operator/",
+		},
+		{
+			Name:        "missing-secret",
+			Description: "a Secret is missing from the secret store",
+			LogPatterns: []string{
+				`(?i)failed to populate secrets`,
+				`(?i)secrets? "?[\w.-]+"? not found`,
+				`(?i)could not find (the )?secret`,
+				`(?i)externalsecret.*(not ready|error)`,
+			},
+			EventPatterns: []string{
+				`(?i)^(SecretSyncedError|UpdateFailed)\b`,
+				`(?i)secrets? "?[\w.-]+"? not found`,
+			},
+			Fix:    "populate the missing secret values in the secret store with: jx secret edit then trigger the boot Job again with: jx admin trigger",
+			DocURL: "https://jayex.io/v3/admin/setup/secrets/",
+		},
+		{
+			Name:        "helm-upgrade-timeout",
+			Description: "a helm release did not become ready in time",
+			LogPatterns: []string{
+				`UPGRADE FAILED: timed out waiting for the condition`,
+				`INSTALLATION FAILED: timed out waiting for the condition`,
+				`(?i)\bcontext deadline exceeded\b`,
+			},
+			Fix:    "check the pods of the failing release with: kubectl get pods and kubectl describe pod to find out why they are not ready. If the release is just slow to start increase its helm timeout",
+			DocURL: "https://jayex.io/v3/admin/troubleshooting/",
+		},
+		{
+			Name:        "crd-conflict",
+			Description: "a CustomResourceDefinition conflicts with an existing resource",
+			LogPatterns: []string{
+				`(?i)rendered manifests contain a resource that already exists`,
+				`(?i)customresourcedefinitions?.*(already exists|is invalid|invalid ownership metadata)`,
+				`(?i)invalid ownership metadata`,
+				`(?i)metadata\.annotations: too long`,
+			},
+			Fix:    "the resource is owned by another helm release or was created outside of helm. Remove the conflicting resource or adopt it by adding the meta.helm.sh/release-name and meta.helm.sh/release-namespace annotations. CRDs too large for client side apply need server side apply",
+			DocURL: "https://jayex.io/v3/admin/troubleshooting/",
+		},
+		{
+			Name:        "image-pull-backoff",
+			Description: "a container image could not be pulled",
+			LogPatterns: []string{
+				`\b(ImagePullBackOff|ErrImagePull)\b`,
+			},
+			EventPatterns: []string{
+				`\b(ImagePullBackOff|ErrImagePull)\b`,
+				`(?i)failed to pull image`,
+			},
+			Fix:    "check the image name and tag exist in the registry and that the image pull secrets of the service account can access it",
+			DocURL: "https://jayex.io/v3/admin/troubleshooting/",
+		},
+	}
+}
+
+// LoadRules loads the user supplied rules from the YAML file
+func LoadRules(path string) ([]*Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rules file %s: %w", path, err)
+	}
+	ruleSet := &RuleSet{}
+	err = yaml.Unmarshal(data, ruleSet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rules file %s: %w", path, err)
+	}
+	for i, r := range ruleSet.Rules {
+		if r.Name == "" {
+			return nil, fmt.Errorf("rule %d in file %s has no name", i+1, path)
+		}
+		if len(r.LogPatterns) == 0 && len(r.EventPatterns) == 0 {
+			return nil, fmt.Errorf("rule %s in file %s has no logPatterns or eventPatterns", r.Name, path)
+		}
+		err = r.compile()
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule %s in file %s: %w", r.Name, path, err)
+		}
+	}
+	return ruleSet.Rules, nil
+}
+
+// MergeRules returns the rules with the overrides replacing any rules of the same name
+func MergeRules(rules, overrides []*Rule) []*Rule {
+	answer := append([]*Rule{}, rules...)
+	for _, o := range overrides {
+		replaced := false
+		for i, r := range answer {
+			if r.Name == o.Name {
+				answer[i] = o
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			answer = append(answer, o)
+		}
+	}
+	return answer
+}
+
+func (r *Rule) compile() error {
+	var err error
+	r.logRegexps, err = compilePatterns(r.LogPatterns)
+	if err != nil {
+		return err
+	}
+	r.eventRegexps, err = compilePatterns(r.EventPatterns)
+	return err
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var answer []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse pattern %s: %w", p, err)
+		}
+		answer = append(answer, re)
+	}
+	return answer, nil
+}
+
+// Diagnoser matches the boot log lines and events against the rules
+type Diagnoser struct {
+	rules     []*Rule
+	diagnoses []*Diagnosis
+	matched   map[string]bool
+}
+
+// NewDiagnoser creates a diagnoser for the rules
+func NewDiagnoser(rules []*Rule) (*Diagnoser, error) {
+	for _, r := range rules {
+		if r.logRegexps == nil && r.eventRegexps == nil {
+			err := r.compile()
+			if err != nil {
+				return nil, fmt.Errorf("invalid rule %s: %w", r.Name, err)
+			}
+		}
+	}
+	return &Diagnoser{
+		rules:   rules,
+		matched: map[string]bool{},
+	}, nil
+}
+
+// AddLine matches the line of the boot log against the rules
+func (d *Diagnoser) AddLine(line string) {
+	for _, r := range d.rules {
+		d.match(r, r.logRegexps, line)
+	}
+}
+
+// AddEvent matches the reason and message of an event against the rules
+func (d *Diagnoser) AddEvent(reason, message string) {
+	text := reason + ": " + message
+	for _, r := range d.rules {
+		d.match(r, r.eventRegexps, text)
+	}
+}
+
+// Diagnoses returns the matched rules in the order they were first matched
+func (d *Diagnoser) Diagnoses() []*Diagnosis {
+	return d.diagnoses
+}
+
+func (d *Diagnoser) match(r *Rule, regexps []*regexp.Regexp, text string) {
+	if d.matched[r.Name] {
+		return
+	}
+	for _, re := range regexps {
+		if re.MatchString(text) {
+			d.matched[r.Name] = true
+			d.diagnoses = append(d.diagnoses, &Diagnosis{
+				Name:        r.Name,
+				Description: r.Description,
+				Fix:         r.Fix,
+				DocURL:      r.DocURL,
+				Evidence:    text,
+			})
+			return
+		}
+	}
+}
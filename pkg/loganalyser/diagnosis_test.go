@@ -0,0 +1,88 @@
+package loganalyser_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/loganalyser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnoseDefaultRules(t *testing.T) {
+	d, err := loganalyser.NewDiagnoser(loganalyser.DefaultRules())
+	require.NoError(t, err, "failed to create diagnoser")
+
+	addLogFile(t, d, "failed.log")
+	d.AddEvent("Failed", `Failed to pull image "ghcr.io/jenkins-x/does-not-exist:1.0.0": rpc error: code = NotFound`)
+	d.AddEvent("Scheduled", "Successfully assigned jx-git-operator/jx-boot-abc to node1")
+
+	diagnoses := d.Diagnoses()
+	require.Len(t, diagnoses, 2, "diagnoses")
+	assert.Equal(t, "helm-upgrade-timeout", diagnoses[0].Name, "first diagnosis")
+	assert.Equal(t, "Error: UPGRADE FAILED: timed out waiting for the condition", diagnoses[0].Evidence, "first diagnosis evidence")
+	assert.NotEmpty(t, diagnoses[0].Fix, "first diagnosis fix")
+	assert.NotEmpty(t, diagnoses[0].DocURL, "first diagnosis doc URL")
+	assert.Equal(t, "image-pull-backoff", diagnoses[1].Name, "second diagnosis")
+}
+
+func TestDiagnoseGitTokenExpired(t *testing.T) {
+	d, err := loganalyser.NewDiagnoser(loganalyser.DefaultRules())
+	require.NoError(t, err, "failed to create diagnoser")
+
+	d.AddLine("Cloning into 'source'...")
+	d.AddLine("remote: Invalid username or password.")
+	d.AddLine("fatal: Authentication failed for 'https://github.com/myorg/environment-mycluster-dev.git/'")
+
+	diagnoses := d.Diagnoses()
+	require.Len(t, diagnoses, 1, "diagnoses")
+	assert.Equal(t, "git-token-expired", diagnoses[0].Name, "diagnosis")
+	assert.Equal(t, "remote: Invalid username or password.", diagnoses[0].Evidence, "evidence")
+}
+
+func TestDiagnoseUserRules(t *testing.T) {
+	userRules, err := loganalyser.LoadRules(filepath.Join("test_data", "rules.yaml"))
+	require.NoError(t, err, "failed to load rules")
+
+	rules := loganalyser.MergeRules(loganalyser.DefaultRules(), userRules)
+	assert.Len(t, rules, len(loganalyser.DefaultRules())+1, "rules should replace the built-in rule with the same name")
+
+	d, err := loganalyser.NewDiagnoser(rules)
+	require.NoError(t, err, "failed to create diagnoser")
+
+	addLogFile(t, d, "failed.log")
+	d.AddEvent("FailedCreate", `pods "jx-boot-abc" is forbidden: exceeded quota: compute-resources`)
+
+	diagnoses := d.Diagnoses()
+	require.Len(t, diagnoses, 2, "diagnoses")
+	assert.Equal(t, "helm-upgrade-timeout", diagnoses[0].Name, "first diagnosis")
+	assert.Equal(t, "waiting for the load balancer", diagnoses[0].Evidence, "user rule should replace the built-in rule")
+	assert.Equal(t, "custom-quota", diagnoses[1].Name, "second diagnosis")
+}
+
+func TestLoadRulesInvalid(t *testing.T) {
+	dir := t.TempDir()
+	for name, text := range map[string]string{
+		"no-name.yaml":     "rules:\n- logPatterns: [foo]\n",
+		"no-patterns.yaml": "rules:\n- name: foo\n",
+		"bad-regex.yaml":   "rules:\n- name: foo\n  logPatterns: ['(']\n",
+	} {
+		path := filepath.Join(dir, name)
+		err := os.WriteFile(path, []byte(text), 0o600)
+		require.NoError(t, err, "failed to save %s", path)
+
+		_, err = loganalyser.LoadRules(path)
+		require.Error(t, err, "should fail to load %s", name)
+		t.Logf("got expected error for %s: %s\n", name, err.Error())
+	}
+}
+
+func addLogFile(t *testing.T, d *loganalyser.Diagnoser, name string) {
+	data, err := os.ReadFile(filepath.Join("test_data", name))
+	require.NoError(t, err, "failed to load %s", name)
+	for _, line := range strings.Split(string(data), "\n") {
+		d.AddLine(line)
+	}
+}
@@ -0,0 +1,171 @@
+package netconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/http/httpproxy"
+)
+
+const (
+	// CAFileKey the key of the CA bundle in the ConfigMap used by the git operator
+	CAFileKey = "ca.crt"
+)
+
+// Config the custom CA bundle and HTTP proxy configuration used to talk to the git server
+type Config struct {
+	CAFile     string
+	HTTPProxy  string
+	HTTPSProxy string
+	NoProxy    string
+}
+
+// AddFlags adds the CLI flags for the configuration
+func (c *Config) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&c.CAFile, "ca-file", "", "", "the PEM encoded CA bundle used to verify the TLS certificate of the git server")
+	cmd.Flags().StringVarP(&c.HTTPProxy, "http-proxy", "", "", "the HTTP proxy used to access the git server. Defaults to $HTTP_PROXY for the local git client")
+	cmd.Flags().StringVarP(&c.HTTPSProxy, "https-proxy", "", "", "the HTTPS proxy used to access the git server. Defaults to $HTTPS_PROXY for the local git client")
+	cmd.Flags().StringVarP(&c.NoProxy, "no-proxy", "", "", "the comma separated list of hosts, domains and CIDRs which should not use the proxy. Defaults to $NO_PROXY for the local git client")
+}
+
+// IsEmpty returns true if there is no custom CA or proxy configuration
+func (c *Config) IsEmpty() bool {
+	return c.CAFile == "" && !c.HasProxy()
+}
+
+// HasProxy returns true if a proxy has been configured
+func (c *Config) HasProxy() bool {
+	return c.HTTPProxy != "" || c.HTTPSProxy != "" || c.NoProxy != ""
+}
+
+// LoadCA loads and validates the CA bundle
+func (c *Config) LoadCA() ([]byte, error) {
+	data, err := os.ReadFile(c.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load CA file %s: %w", c.CAFile, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM encoded certificates found in CA file %s", c.CAFile)
+	}
+	return data, nil
+}
+
+// CertPool returns the system certificates along with the CA bundle
+func (c *Config) CertPool() (*x509.CertPool, error) {
+	data, err := c.LoadCA()
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	pool.AppendCertsFromPEM(data)
+	return pool, nil
+}
+
+// ProxyConfig returns the proxy configuration using the environment variables for any values not specified
+func (c *Config) ProxyConfig() *httpproxy.Config {
+	answer := httpproxy.FromEnvironment()
+	if c.HTTPProxy != "" {
+		answer.HTTPProxy = c.HTTPProxy
+	}
+	if c.HTTPSProxy != "" {
+		answer.HTTPSProxy = c.HTTPSProxy
+	}
+	if c.NoProxy != "" {
+		answer.NoProxy = c.NoProxy
+	}
+	return answer
+}
+
+// Env returns the environment variables which configure git and curl to use the CA bundle and proxies
+func (c *Config) Env() (map[string]string, error) {
+	answer := map[string]string{}
+	if c.CAFile != "" {
+		path, err := filepath.Abs(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the absolute path of %s: %w", c.CAFile, err)
+		}
+		answer["GIT_SSL_CAINFO"] = path
+	}
+	// curl only supports the lower case http_proxy so lets set both
+	for name, value := range map[string]string{
+		"HTTP_PROXY":  c.HTTPProxy,
+		"HTTPS_PROXY": c.HTTPSProxy,
+		"NO_PROXY":    c.NoProxy,
+	} {
+		if value != "" {
+			answer[name] = value
+			answer[strings.ToLower(name)] = value
+		}
+	}
+	return answer, nil
+}
+
+// HTTPClient returns a HTTP client which uses the CA bundle and proxies or nil if there is no custom configuration.
+// The default HTTP transport is cloned so that other HTTP clients in this process are not affected
+func (c *Config) HTTPClient() (*http.Client, error) {
+	if c.IsEmpty() {
+		return nil, nil
+	}
+	defaultTransport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return nil, errors.New("cannot configure the CA and proxies as the default HTTP transport is not a *http.Transport")
+	}
+	t := defaultTransport.Clone()
+	if c.CAFile != "" {
+		pool, err := c.CertPool()
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+		if t.TLSClientConfig != nil {
+			tlsConfig = t.TLSClientConfig.Clone()
+		}
+		tlsConfig.RootCAs = pool
+		t.TLSClientConfig = tlsConfig
+	}
+	if c.HasProxy() {
+		proxyFunc := c.ProxyConfig().ProxyFunc()
+		t.Proxy = func(req *http.Request) (*url.URL, error) {
+			return proxyFunc(req.URL)
+		}
+	}
+	return &http.Client{Transport: t}, nil
+}
+
+// CommandRunner returns a command runner which passes the environment variables for the CA bundle and proxies
+// to any git commands. Any environment variables already specified on the command take precedence
+func (c *Config) CommandRunner(runner cmdrunner.CommandRunner) (cmdrunner.CommandRunner, error) {
+	if c.IsEmpty() {
+		return runner, nil
+	}
+	env, err := c.Env()
+	if err != nil {
+		return nil, err
+	}
+	return func(command *cmdrunner.Command) (string, error) {
+		if command.Name == "git" {
+			if command.Env == nil {
+				command.Env = map[string]string{}
+			}
+			for k, v := range env {
+				if _, ok := command.Env[k]; !ok {
+					command.Env[k] = v
+				}
+			}
+		}
+		return runner(command)
+	}, nil
+}
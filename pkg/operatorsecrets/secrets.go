@@ -0,0 +1,186 @@
+package operatorsecrets
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jenkins-x-plugins/jx-admin/pkg/bootjobs"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// DefaultSecretName the default name of the git operator Secret created by the git operator chart
+	DefaultSecretName = "jx-boot"
+
+	// KeyURL the key in the Secret for the git clone URL
+	KeyURL = "url"
+
+	// KeyUsername the key in the Secret for the git username
+	KeyUsername = "username"
+
+	// KeyPassword the key in the Secret for the git token
+	KeyPassword = "password"
+
+	// KeyNamespace the key in the Secret for the namespace the boot Jobs of the repository run in
+	KeyNamespace = "namespace"
+
+	// helmManagedByLabel the label helm adds to the resources it manages
+	helmManagedByLabel = "app.kubernetes.io/managed-by"
+
+	// helmReleaseNameAnnotation the annotation helm adds with the name of the release which owns a resource
+	helmReleaseNameAnnotation = "meta.helm.sh/release-name"
+
+	// helmReleaseNamespaceAnnotation the annotation helm adds with the namespace of the release which owns a resource
+	helmReleaseNamespaceAnnotation = "meta.helm.sh/release-namespace"
+
+	// helmResourcePolicyAnnotation the annotation which stops helm deleting a resource which is no longer in the chart
+	helmResourcePolicyAnnotation = "helm.sh/resource-policy"
+)
+
+// ExternalSecretResources the resources of the ExternalSecret kinds we look for when referencing an existing Secret
+var ExternalSecretResources = []schema.GroupVersionResource{
+	{Group: "external-secrets.io", Version: "v1beta1", Resource: "externalsecrets"},
+	{Group: "kubernetes-client.io", Version: "v1", Resource: "externalsecrets"},
+}
+
+// Credentials the git credentials the git operator uses to clone a git repository
+type Credentials struct {
+	URL      string
+	Username string
+	Password string
+
+	// Namespace the optional namespace the boot Jobs of the repository run in
+	Namespace string
+}
+
+// NewSecret creates a new git operator Secret for the given credentials
+func NewSecret(ns, name string, creds *Credentials) *corev1.Secret {
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ns,
+			Labels: map[string]string{
+				bootjobs.LabelGitOperatorKind: bootjobs.GitOperatorKind,
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			KeyURL:      []byte(creds.URL),
+			KeyUsername: []byte(creds.Username),
+			KeyPassword: []byte(creds.Password),
+		},
+	}
+	if creds.Namespace != "" {
+		secret.Data[KeyNamespace] = []byte(creds.Namespace)
+	}
+	return secret
+}
+
+// GetCredentials returns the git credentials in the given Secret
+func GetCredentials(secret *corev1.Secret) *Credentials {
+	answer := &Credentials{}
+	if secret == nil {
+		return answer
+	}
+	answer.URL = secretValue(secret, KeyURL)
+	answer.Username = secretValue(secret, KeyUsername)
+	answer.Password = secretValue(secret, KeyPassword)
+	answer.Namespace = secretValue(secret, KeyNamespace)
+	return answer
+}
+
+func secretValue(secret *corev1.Secret, key string) string {
+	if secret.Data != nil {
+		if value, ok := secret.Data[key]; ok {
+			return string(value)
+		}
+	}
+	if secret.StringData != nil {
+		return secret.StringData[key]
+	}
+	return ""
+}
+
+// EnsureSecret creates the given Secret or updates the labels and replaces the data of the existing Secret.
+//
+// If the existing Secret was created by the git operator chart it is released from helm so that the next
+// helm upgrade, which no longer renders the Secret, does not delete it
+func EnsureSecret(client kubernetes.Interface, secret *corev1.Secret) (*corev1.Secret, error) {
+	ctx := context.TODO()
+	ns := secret.Namespace
+	name := secret.Name
+	secretInterface := client.CoreV1().Secrets(ns)
+	current, err := secretInterface.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to find Secret %s in namespace %s: %w", name, ns, err)
+		}
+		answer, err := secretInterface.Create(ctx, secret, metav1.CreateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Secret %s in namespace %s: %w", name, ns, err)
+		}
+		return answer, nil
+	}
+
+	if current.Labels == nil {
+		current.Labels = map[string]string{}
+	}
+	for k, v := range secret.Labels {
+		current.Labels[k] = v
+	}
+	if current.Annotations[helmReleaseNameAnnotation] != "" {
+		delete(current.Labels, helmManagedByLabel)
+		delete(current.Annotations, helmReleaseNameAnnotation)
+		delete(current.Annotations, helmReleaseNamespaceAnnotation)
+		// helm deletes the resources of the previous release which are not rendered by the upgrade
+		current.Annotations[helmResourcePolicyAnnotation] = "keep"
+	}
+	current.Data = secret.Data
+	current.StringData = nil
+	answer, err := secretInterface.Update(ctx, current, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update Secret %s in namespace %s: %w", name, ns, err)
+	}
+	return answer, nil
+}
+
+// EnsureSecretLabel ensures the existing Secret has the label so that the git operator watches it
+func EnsureSecretLabel(client kubernetes.Interface, secret *corev1.Secret) error {
+	if secret.Labels[bootjobs.LabelGitOperatorKind] == bootjobs.GitOperatorKind {
+		return nil
+	}
+	if secret.Labels == nil {
+		secret.Labels = map[string]string{}
+	}
+	secret.Labels[bootjobs.LabelGitOperatorKind] = bootjobs.GitOperatorKind
+	_, err := client.CoreV1().Secrets(secret.Namespace).Update(context.TODO(), secret, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to label Secret %s in namespace %s: %w", secret.Name, secret.Namespace, err)
+	}
+	return nil
+}
+
+// FindExternalSecret returns true if there is an ExternalSecret of the given name in the namespace
+func FindExternalSecret(client dynamic.Interface, ns, name string) (bool, error) {
+	for _, gvr := range ExternalSecretResources {
+		_, err := client.Resource(gvr).Namespace(ns).Get(context.TODO(), name, metav1.GetOptions{})
+		if err == nil {
+			return true, nil
+		}
+		// a missing CRD is also reported as not found
+		if !apierrors.IsNotFound(err) {
+			return false, fmt.Errorf("failed to find %s %s in namespace %s: %w", gvr.String(), name, ns, err)
+		}
+	}
+	return false, nil
+}